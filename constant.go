@@ -1,12 +1,12 @@
 package zlog
 
 const (
-	// log level
-	LL_DEBUG = "[DEBUG]"
-	LL_INFO  = "[INFO]"
-	LL_WARN  = "[WARN]"
-	LL_ERROR = "[ERROR]"
-	LL_FATAL = "[FATAL]"
+	// log level，兼容旧版本，等同于 *Level
+	LL_DEBUG = DebugLevel
+	LL_INFO  = InfoLevel
+	LL_WARN  = WarnLevel
+	LL_ERROR = ErrorLevel
+	LL_FATAL = FatalLevel
 
 	// obj
 	OBJ_INIT        = "INIT"
@@ -15,6 +15,8 @@ const (
 	OBJ_RB          = "RB"          // 请求开始
 	OBJ_REQ         = "REQ"         // 请求处理过程中
 	OBJ_RE          = "RE"          // 请求结束
+	OBJ_ZLOG        = "ZLOG"        // zlog 内部日志
+	OBJ_ZAP         = "ZAP"         // 通过 ZapLogger 打印且未命名的日志
 
 	// log key
 	LK_TIMESTAMP    = "ts"
@@ -33,4 +35,11 @@ const (
 	LK_REQ_PARAMS   = "reqParams"
 	LK_RET_DATA     = "retData"
 	LK_RET_CODE     = "retCode"
+	LK_RET_SIZE     = "retSize" // 返回数据的大小
+	LK_STACK        = "stack"
+
+	// W3C trace context，见 TraceContext
+	LK_TRACE_ID       = "traceId"
+	LK_SPAN_ID        = "spanId"
+	LK_PARENT_SPAN_ID = "parentSpanId"
 )
@@ -0,0 +1,67 @@
+package zlog
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type ctxKey struct{}
+
+type ctxValue struct {
+	reqId  string
+	fields []Field
+	trace  TraceContext // 见 ContextWithTrace
+}
+
+// NewContext 返回携带 reqId 及 fields 的 context，配合 LogCtx* 方法使用
+// 如果 ctx 中已经携带了 reqId/fields：reqId 为空时沿用原 reqId，fields 追加在原 fields 之后；TraceContext 保持不变
+func NewContext(ctx context.Context, reqId string, fields ...Field) context.Context {
+	v := &ctxValue{reqId: reqId}
+	if parent := fromContext(ctx); parent != nil {
+		if v.reqId == "" {
+			v.reqId = parent.reqId
+		}
+		v.trace = parent.trace
+		v.fields = make([]Field, 0, len(parent.fields)+len(fields))
+		v.fields = append(v.fields, parent.fields...)
+	}
+	v.fields = append(v.fields, fields...)
+	return context.WithValue(ctx, ctxKey{}, v)
+}
+
+// 获取 ctx 中携带的 reqId，不存在时返回空字符串
+func ReqIdFromContext(ctx context.Context) string {
+	if v := fromContext(ctx); v != nil {
+		return v.reqId
+	}
+	return ""
+}
+
+func fromContext(ctx context.Context) *ctxValue {
+	if ctx == nil {
+		return nil
+	}
+	v, _ := ctx.Value(ctxKey{}).(*ctxValue)
+	return v
+}
+
+// 拼装 LogCtx* 的日志字段：obj、reqId（不存在时省略）、trace 字段（不存在时省略）、fields、With 绑定的字段、ctx 中携带的 fields
+func (v *ctxValue) logFields(obj string, fields ...Field) []Field {
+	if v == nil {
+		return append([]Field{zap.String(LK_OBJ, obj)}, fields...)
+	}
+	traceFields := v.trace.Fields()
+	ret := make([]Field, 0, 3+len(traceFields)+len(fields)+len(v.fields))
+	ret = append(ret, zap.String(LK_OBJ, obj))
+	if v.reqId != "" {
+		ret = append(ret, zap.String(LK_REQ_ID, v.reqId))
+	}
+	ret = append(ret, traceFields...)
+	ret = append(ret, fields...)
+	if len(v.fields) == 0 {
+		return ret
+	}
+	ret = append(ret, withFieldsMark)
+	return append(ret, v.fields...)
+}
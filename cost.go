@@ -0,0 +1,59 @@
+package zlog
+
+import (
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// cost 的输出单位，见 LogConfig.CostUnit
+const (
+	COST_UNIT_MS       = "ms"       // 毫秒整数（默认）
+	COST_UNIT_US       = "us"       // 微秒整数，也可写作 "µs"
+	COST_UNIT_NS       = "ns"       // 纳秒整数
+	COST_UNIT_FLOAT_MS = "float-ms" // 毫秒，保留 3 位小数，如 0.153
+)
+
+// costDuration 用于标记耗时字段，由 encoder 按配置的单位输出
+type costDuration time.Duration
+
+// Cost 返回耗时字段（key 为 cost），按 LogConfig.CostUnit 配置的单位输出
+// d 建议通过 time.Since 计算，以使用单调时钟
+func Cost(d time.Duration) Field {
+	return costField(LK_COST, d)
+}
+
+func costField(key string, d time.Duration) Field {
+	return zap.Reflect(key, costDuration(d))
+}
+
+// costSince 计算从 startTimeNS（纳秒时间戳）到现在的耗时，startTimeNS <= 0 时返回 0
+func costSince(startTimeNS int64) time.Duration {
+	if startTimeNS <= 0 {
+		return 0
+	}
+	return time.Duration(time.Now().UnixNano() - startTimeNS)
+}
+
+func validCostUnit(unit string) bool {
+	switch unit {
+	case "", COST_UNIT_MS, COST_UNIT_US, "µs", COST_UNIT_NS, COST_UNIT_FLOAT_MS:
+		return true
+	}
+	return false
+}
+
+// appendCost 按 unit 将 d 格式化后追加到 dst
+func appendCost(dst []byte, unit string, d time.Duration) []byte {
+	switch unit {
+	case COST_UNIT_NS:
+		return strconv.AppendInt(dst, int64(d), 10)
+	case COST_UNIT_US, "µs":
+		return strconv.AppendInt(dst, int64(d/time.Microsecond), 10)
+	case COST_UNIT_FLOAT_MS:
+		return strconv.AppendFloat(dst, float64(d)/float64(time.Millisecond), 'f', 3, 64)
+	default:
+		return strconv.AppendInt(dst, int64(d/time.Millisecond), 10)
+	}
+}
@@ -0,0 +1,77 @@
+package zlog
+
+import (
+	"os"
+	"sync"
+)
+
+var (
+	fatalMtx      sync.Mutex
+	shutdownHooks []func()
+	exitFunc      = os.Exit
+)
+
+// RegisterShutdownHook 注册 LogFatal 退出进程前需要执行的方法，如关闭连接、上报监控等
+// 按注册的逆序执行（同 defer），某个方法 panic 不影响其他方法的执行
+func RegisterShutdownHook(hook func()) {
+	fatalMtx.Lock()
+	defer fatalMtx.Unlock()
+	shutdownHooks = append(shutdownHooks, hook)
+}
+
+// 清空已注册的 shutdown hook，返回的 restore 用于恢复原来的 hook，用于测试
+func resetShutdownHooks() (restore func()) {
+	fatalMtx.Lock()
+	defer fatalMtx.Unlock()
+	old := shutdownHooks
+	shutdownHooks = nil
+	return func() {
+		fatalMtx.Lock()
+		shutdownHooks = old
+		fatalMtx.Unlock()
+	}
+}
+
+// SetExitFunc 替换 LogFatal 退出进程的方法（默认为 os.Exit），主要用于测试
+// 返回的 restore 用于恢复原来的方法
+func SetExitFunc(exit func(code int)) (restore func()) {
+	if exit == nil {
+		exit = os.Exit
+	}
+	fatalMtx.Lock()
+	defer fatalMtx.Unlock()
+	old := exitFunc
+	exitFunc = exit
+	return func() {
+		fatalMtx.Lock()
+		exitFunc = old
+		fatalMtx.Unlock()
+	}
+}
+
+func runShutdownHooks() {
+	fatalMtx.Lock()
+	hooks := make([]func(), len(shutdownHooks))
+	copy(hooks, shutdownHooks)
+	fatalMtx.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		runShutdownHook(hooks[i])
+	}
+}
+
+func runShutdownHook(hook func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			internalLog.Printf("[zlog] shutdown hook panic: %v", r)
+		}
+	}()
+	hook()
+}
+
+func exit(code int) {
+	fatalMtx.Lock()
+	f := exitFunc
+	fatalMtx.Unlock()
+	f(code)
+}
@@ -0,0 +1,79 @@
+package zlog
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Field 是附加在日志末尾的 k=v 字段
+// 通过 *w 结尾的方法传入，会按传入顺序追加在固定字段（obj/info/err 等）之后
+type Field = zapcore.Field
+
+func String(key, val string) Field {
+	return zap.String(key, val)
+}
+
+func Int(key string, val int) Field {
+	return zap.Int(key, val)
+}
+
+func Int64(key string, val int64) Field {
+	return zap.Int64(key, val)
+}
+
+func Uint64(key string, val uint64) Field {
+	return zap.Uint64(key, val)
+}
+
+func Float64(key string, val float64) Field {
+	return zap.Float64(key, val)
+}
+
+func Bool(key string, val bool) Field {
+	return zap.Bool(key, val)
+}
+
+// 输出格式同 time.Duration.String()，如 cost=1.5ms
+func Duration(key string, val time.Duration) Field {
+	return zap.Duration(key, val)
+}
+
+func Time(key string, val time.Time) Field {
+	return zap.Time(key, val)
+}
+
+func Stringer(key string, val fmt.Stringer) Field {
+	return zap.Stringer(key, val)
+}
+
+// 以 err=xxx 的形式输出，err 为 nil 时不输出
+func Err(err error) Field {
+	return zap.NamedError(LK_ERR, err)
+}
+
+// 如果 val 是 struct/map 最终会被 json.Marshal 成字符串
+func Any(key string, val interface{}) Field {
+	return zap.Any(key, val)
+}
+
+// With 绑定的字段写在该字段所在的位置，即固定字段之后、调用方传入的 fields 之前，见 zapKVTabEncoder.EncodeEntry
+// SkipType 的字段不会被编码
+var withFieldsMark = zap.Field{Key: "zlog.withFields", Type: zapcore.SkipType}
+
+func isWithFieldsMark(field Field) bool {
+	return field.Type == zapcore.SkipType && field.Key == withFieldsMark.Key
+}
+
+// 拼装固定字段 base 及调用方传入的 fields，With 绑定的字段位于两者之间
+func appendFields(base []Field, fields []Field) []Field {
+	if len(fields) == 0 {
+		return base
+	}
+	ret := make([]Field, 0, len(base)+1+len(fields))
+	ret = append(ret, base...)
+	ret = append(ret, withFieldsMark)
+	return append(ret, fields...)
+}
@@ -1,8 +1,13 @@
 module github.com/fevin/zlog
 
-go 1.14
+go 1.21
 
 require (
 	go.uber.org/zap v1.15.0
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0
 )
+
+require (
+	go.uber.org/atomic v1.6.0 // indirect
+	go.uber.org/multierr v1.5.0 // indirect
+)
@@ -0,0 +1,98 @@
+package zlog
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Level 日志级别，数值与 LogConfig.MaxLogLevel 一致
+type Level int8
+
+const (
+	DebugLevel Level = iota - 1
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+	FatalLevel
+)
+
+var levelNames = map[Level]string{
+	DebugLevel: "DEBUG",
+	InfoLevel:  "INFO",
+	WarnLevel:  "WARN",
+	ErrorLevel: "ERROR",
+	FatalLevel: "FATAL",
+}
+
+// 日志中 logLev 字段输出的内容，如 [INFO]
+var levelTags = map[Level]string{
+	DebugLevel: "[DEBUG]",
+	InfoLevel:  "[INFO]",
+	WarnLevel:  "[WARN]",
+	ErrorLevel: "[ERROR]",
+	FatalLevel: "[FATAL]",
+}
+
+// 返回级别名，如 INFO
+func (l Level) String() string {
+	if name, isOK := levelNames[l]; isOK {
+		return name
+	}
+	return fmt.Sprintf("Level(%d)", l)
+}
+
+func (l Level) tag() string {
+	if tag, isOK := levelTags[l]; isOK {
+		return tag
+	}
+	return "[" + l.String() + "]"
+}
+
+func (l Level) valid() bool {
+	_, isOK := levelNames[l]
+	return isOK
+}
+
+// ParseLevel 解析日志级别，支持以下格式（不区分大小写）：
+// warn / WARN / [WARN] / 1
+func ParseLevel(text string) (Level, error) {
+	s := strings.ToUpper(strings.TrimSpace(text))
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		s = s[1 : len(s)-1]
+	}
+	for l, name := range levelNames {
+		if s == name {
+			return l, nil
+		}
+	}
+	if n, err := strconv.ParseInt(s, 10, 8); err == nil && Level(n).valid() {
+		return Level(n), nil
+	}
+	return InfoLevel, fmt.Errorf("zlog level is error: the level[%s] doesnot exist!", text)
+}
+
+func (l Level) MarshalText() ([]byte, error) {
+	if !l.valid() {
+		return nil, fmt.Errorf("zlog level is error: the level[%d] doesnot exist!", l)
+	}
+	return []byte(l.String()), nil
+}
+
+func (l *Level) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// 兼容数值形式的配置，如 "MaxLogLevel": -1；null 同 encoding/json 的约定，不修改 l
+func (l *Level) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	return l.UnmarshalText(bytes.Trim(data, `"`))
+}
@@ -0,0 +1,171 @@
+package zlog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levelFilter 决定 program.log 输出哪些日志：
+// - level：全局日志级别，即 LogConfig.MaxLogLevel
+// - objLevels：按 obj 覆盖的日志级别，即 LogConfig.ObjLogLevel
+//
+// 作为 zapcore.LevelEnabler 时，取全局级别与所有 obj 级别中最低的一个；
+// 具体某条日志是否输出，在编码之前由 objEnabled 根据 obj 判断
+// obj 级别只作用于 program.log，error-program.log 始终输出所有 ERROR 及以上的日志
+type levelFilter struct {
+	level     zap.AtomicLevel
+	objLevels atomic.Value // *objLevelRules
+}
+
+func newLevelFilter(level Level, objLevels *objLevelRules) *levelFilter {
+	f := &levelFilter{level: zap.NewAtomicLevelAt(getZapLevel(level))}
+	f.objLevels.Store(objLevels)
+	return f
+}
+
+func (f *levelFilter) rules() *objLevelRules {
+	return f.objLevels.Load().(*objLevelRules)
+}
+
+func (f *levelFilter) Enabled(lvl zapcore.Level) bool {
+	if f.level.Enabled(lvl) {
+		return true
+	}
+	rules := f.rules()
+	return rules != nil && lvl >= rules.minZapLevel
+}
+
+// obj 的日志是否输出到 program.log，未配置 obj 级别时交由 core 判断
+// 与 obj 级别比较时使用 Level 本身，FATAL 的日志不会因为按 ERROR 输出而被 FATAL 的 obj 级别过滤
+func (f *levelFilter) objEnabled(logLevel Level, obj string) bool {
+	rules := f.rules()
+	if rules == nil {
+		return true
+	}
+	zapLevel, isOK := getZapLogLevel(logLevel)
+	if !isOK {
+		return true
+	}
+	if level, isOK := rules.match(obj); isOK {
+		return logLevel >= level
+	}
+	return f.level.Enabled(zapLevel)
+}
+
+// 只写入 error-program.log 的日志（obj 级别高于日志级别的 ERROR、FATAL 日志）携带该字段，由 programCore 跳过
+// SkipType 的字段不会被编码
+var skipProgramLogField = zap.Field{Key: "zlog.skipProgramLog", Type: zapcore.SkipType}
+
+func skipProgramLog(fields []zapcore.Field) bool {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Type == zapcore.SkipType && fields[i].Key == skipProgramLogField.Key {
+			return true
+		}
+	}
+	return false
+}
+
+// programCore 是 program.log 的 core，跳过携带 skipProgramLogField 的日志
+type programCore struct {
+	zapcore.Core
+}
+
+func (c programCore) With(fields []zapcore.Field) zapcore.Core {
+	return programCore{c.Core.With(fields)}
+}
+
+func (c programCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c programCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if skipProgramLog(fields) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
+
+type objLevelPrefix struct {
+	prefix string
+	level  Level
+}
+
+// objLevelRules 按 obj 覆盖的日志级别，支持两种写法：
+// - 完整的 obj，如 "MYSQL"
+// - 以 * 结尾的前缀，如 "MYSQL*"，可匹配 MYSQL、MYSQL_SLAVE 等；"*" 匹配所有 obj
+// 同时匹配多条时，完整的 obj 优先，其次是最长的前缀
+type objLevelRules struct {
+	levels      map[string]Level // 原始配置
+	exact       map[string]Level
+	prefixes    []objLevelPrefix // 按前缀长度倒序
+	minZapLevel zapcore.Level
+}
+
+// 解析 obj 级别配置，levels 为空时返回 nil
+func newObjLevelRules(levels map[string]Level) (*objLevelRules, error) {
+	if len(levels) == 0 {
+		return nil, nil
+	}
+
+	rules := &objLevelRules{
+		levels:      make(map[string]Level, len(levels)),
+		exact:       make(map[string]Level, len(levels)),
+		minZapLevel: zapcore.FatalLevel,
+	}
+	for obj, level := range levels {
+		if !level.valid() {
+			return nil, fmt.Errorf("zlog level is error: the level[%d] of obj[%s] doesnot exist!", level, obj)
+		}
+		prefix := strings.TrimSuffix(obj, "*")
+		if strings.Contains(prefix, "*") {
+			return nil, fmt.Errorf("zlog obj level is error: only trailing * is supported, obj[%s]", obj)
+		}
+
+		rules.levels[obj] = level
+		if prefix == obj {
+			rules.exact[obj] = level
+		} else {
+			rules.prefixes = append(rules.prefixes, objLevelPrefix{prefix: prefix, level: level})
+		}
+		// 与打印日志时的 zap level 一致，FATAL 按 ERROR
+		if zapLevel, _ := getZapLogLevel(level); zapLevel < rules.minZapLevel {
+			rules.minZapLevel = zapLevel
+		}
+	}
+	sort.Slice(rules.prefixes, func(i, j int) bool {
+		return len(rules.prefixes[i].prefix) > len(rules.prefixes[j].prefix)
+	})
+	return rules, nil
+}
+
+func (rules *objLevelRules) match(obj string) (Level, bool) {
+	if level, isOK := rules.exact[obj]; isOK {
+		return level, true
+	}
+	for _, p := range rules.prefixes {
+		if strings.HasPrefix(obj, p.prefix) {
+			return p.level, true
+		}
+	}
+	return InfoLevel, false
+}
+
+// 返回配置的副本
+func (rules *objLevelRules) copyLevels() map[string]Level {
+	if rules == nil {
+		return nil
+	}
+	levels := make(map[string]Level, len(rules.levels))
+	for obj, level := range rules.levels {
+		levels[obj] = level
+	}
+	return levels
+}
@@ -0,0 +1,59 @@
+package zlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// levelHandler 用于在运行时查看、调整日志级别，请求及返回的数据均为 JSON：
+//
+//	GET  => {"level":"INFO"}
+//	PUT  {"level":"debug"} => {"level":"DEBUG"}
+//
+// level 的格式见 ParseLevel，出错时返回 {"error":"xxx"}
+type levelHandler struct {
+	logger *Logger
+}
+
+type levelPayload struct {
+	Level *Level `json:"level"`
+}
+
+type levelErrorPayload struct {
+	Error string `json:"error"`
+}
+
+func (this *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req levelPayload
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			enc.Encode(levelErrorPayload{Error: fmt.Sprintf("request body must be well-formed JSON: %v", err)})
+			return
+		}
+		if req.Level == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			enc.Encode(levelErrorPayload{Error: "must specify a logging level"})
+			return
+		}
+		if err := this.logger.SetLevel(*req.Level); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			enc.Encode(levelErrorPayload{Error: err.Error()})
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		enc.Encode(levelErrorPayload{Error: "only GET and PUT are supported"})
+		return
+	}
+
+	level := this.logger.GetLevel()
+	enc.Encode(levelPayload{Level: &level})
+}
@@ -0,0 +1,222 @@
+package zlog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	for text, want := range map[string]Level{
+		"warn":    WarnLevel,
+		"WARN":    WarnLevel,
+		"[WARN]":  WarnLevel,
+		"1":       WarnLevel,
+		"-1":      DebugLevel,
+		" debug ": DebugLevel,
+		"[FATAL]": FatalLevel,
+	} {
+		l, err := ParseLevel(text)
+		if err != nil || l != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", text, l, err, want)
+		}
+	}
+	for _, text := range []string{"", "INF", "4", "[INFO"} {
+		if _, err := ParseLevel(text); err == nil {
+			t.Errorf("ParseLevel(%q) should return error", text)
+		}
+	}
+}
+
+func TestLevelJSON(t *testing.T) {
+	var conf LogConfig
+	if err := json.Unmarshal([]byte(`{"MaxLogLevel":-1}`), &conf); err != nil || conf.MaxLogLevel != DebugLevel {
+		t.Errorf("unmarshal number: %v, %v", conf.MaxLogLevel, err)
+	}
+	if err := json.Unmarshal([]byte(`{"MaxLogLevel":"warn"}`), &conf); err != nil || conf.MaxLogLevel != WarnLevel {
+		t.Errorf("unmarshal name: %v, %v", conf.MaxLogLevel, err)
+	}
+	if err := json.Unmarshal([]byte(`{"MaxLogLevel":"verbose"}`), &conf); err == nil {
+		t.Error("unmarshal invalid level should return error")
+	}
+	// null 不修改原来的值
+	if err := json.Unmarshal([]byte(`{"MaxLogLevel":null,"UnknownLevelFallback":null}`), &conf); err != nil || conf.MaxLogLevel != WarnLevel {
+		t.Errorf("unmarshal null: %v, %v", conf.MaxLogLevel, err)
+	}
+	var level Level = ErrorLevel
+	if err := level.UnmarshalJSON([]byte("null")); err != nil || level != ErrorLevel {
+		t.Errorf("unmarshal null: %v, %v", level, err)
+	}
+
+	bs, err := json.Marshal(ErrorLevel)
+	if err != nil || string(bs) != `"ERROR"` {
+		t.Errorf("marshal: %s, %v", bs, err)
+	}
+	if LL_INFO.String() != "INFO" || LL_INFO.tag() != "[INFO]" || Level(7).String() != "Level(7)" {
+		t.Error("level string is error")
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	l, dir := newTestLogger(t, nil)
+	child := l.With(String("module", "cache"))
+	child.Log(LL_DEBUG, "TEST_OBJ", "debug off")
+	if err := l.SetLevel(DebugLevel); err != nil {
+		t.Fatal(err)
+	}
+	child.Log(LL_DEBUG, "TEST_OBJ", "debug on")
+	if l.GetLevel() != DebugLevel || child.GetLevel() != DebugLevel {
+		t.Errorf("level is error: %v", l.GetLevel())
+	}
+	if err := l.SetLevel(Level(5)); err == nil {
+		t.Error("invalid level should return error")
+	}
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	if strings.Contains(content, "debug off") || !strings.Contains(content, "debug on") {
+		t.Errorf("SetLevel is not applied: %s", content)
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	l, _ := newTestLogger(t, nil)
+	defer l.Close()
+	h := l.LevelHandler()
+
+	for _, c := range []struct {
+		method, body string
+		code         int
+		resp         string
+	}{
+		{http.MethodGet, "", http.StatusOK, `{"level":"INFO"}`},
+		{http.MethodPut, `{"level":"debug"}`, http.StatusOK, `{"level":"DEBUG"}`},
+		{http.MethodGet, "", http.StatusOK, `{"level":"DEBUG"}`},
+		{http.MethodPut, `{"level":-1}`, http.StatusOK, `{"level":"DEBUG"}`},
+		{http.MethodPut, `{"level":"verbose"}`, http.StatusBadRequest, `{"error":`},
+		{http.MethodPut, `{}`, http.StatusBadRequest, `{"error":"must specify a logging level"}`},
+		{http.MethodPost, "", http.StatusMethodNotAllowed, `{"error":`},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(c.method, "/log/level", strings.NewReader(c.body)))
+		if w.Code != c.code || !strings.HasPrefix(w.Body.String(), c.resp) {
+			t.Errorf("%s %s: got %d %s", c.method, c.body, w.Code, w.Body.String())
+		}
+	}
+	if l.GetLevel() != DebugLevel {
+		t.Errorf("level is error: %v", l.GetLevel())
+	}
+}
+
+func TestObjLevel(t *testing.T) {
+	var conf LogConfig
+	if err := json.Unmarshal([]byte(`{"ObjLogLevel":{"CACHE":"WARN","MYSQL*":"DEBUG","MYSQL_SLAVE*":"ERROR"}}`), &conf); err != nil {
+		t.Fatal(err)
+	}
+	l, dir := newTestLogger(t, &conf)
+	l.Log(LL_INFO, "CACHE", "cache info")
+	l.Log(LL_WARN, "CACHE", "cache warn")
+	l.Log(LL_DEBUG, "MYSQL", "mysql debug")
+	l.Log(LL_WARN, "MYSQL_SLAVE_1", "slave warn")
+	l.Log(LL_DEBUG, "OTHER", "other debug")
+	l.Log(LL_INFO, "OTHER", "other info")
+	var cnt countStringer
+	l.Logf(LL_INFO, "CACHE", "cache %s", &cnt)
+
+	if err := l.SetObjLevels(map[string]Level{"OTHER": DebugLevel}); err != nil {
+		t.Fatal(err)
+	}
+	l.Log(LL_DEBUG, "OTHER", "runtime debug")
+	l.Log(LL_INFO, "CACHE", "runtime cache info")
+	if levels := l.GetObjLevels(); len(levels) != 1 || levels["OTHER"] != DebugLevel {
+		t.Errorf("obj levels is error: %v", levels)
+	}
+	for _, levels := range []map[string]Level{{"MY*SQL": WarnLevel}, {"MYSQL": Level(5)}} {
+		if err := l.SetObjLevels(levels); err == nil {
+			t.Errorf("invalid obj levels should return error: %v", levels)
+		}
+	}
+	l.Close()
+
+	if cnt != 0 {
+		t.Errorf("disabled obj should not format, format count: %d", cnt)
+	}
+	content := readLogFile(t, dir, "test.log")
+	for info, want := range map[string]bool{
+		"cache info":         false,
+		"cache warn":         true,
+		"mysql debug":        true,
+		"slave warn":         false,
+		"other debug":        false,
+		"other info":         true,
+		"runtime debug":      true,
+		"runtime cache info": true,
+	} {
+		if strings.Contains(content, "info="+info+"\n") != want {
+			t.Errorf("%q should be logged: %v", info, want)
+		}
+	}
+}
+
+// obj 级别只作用于 program.log，error-test.log 依然输出所有 ERROR 及以上的日志
+func TestObjLevelErrorLog(t *testing.T) {
+	var conf LogConfig
+	if err := json.Unmarshal([]byte(`{"MaxLogLevel":"FATAL","ObjLogLevel":{"QUIET":"FATAL"}}`), &conf); err != nil {
+		t.Fatal(err)
+	}
+	l, dir := newTestLogger(t, &conf)
+	l.Log(LL_FATAL, "QUIET", "quiet fatal")
+	l.Log(LL_ERROR, "QUIET", "quiet error")
+	l.Log(LL_ERROR, "OTHER", "other error")
+	l.Logf(LL_ERROR, "QUIET", "quiet %s", "errorf")
+	l.ZapLogger().Named("QUIET").Error("zap error")
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	errContent := readLogFile(t, dir, "error-test.log")
+	for info, want := range map[string]bool{
+		"quiet fatal":  true,
+		"quiet error":  false,
+		"other error":  false,
+		"quiet errorf": false,
+		"zap error":    false,
+	} {
+		if strings.Contains(content, "info="+info+"\n") != want {
+			t.Errorf("%q should be logged in test.log: %v", info, want)
+		}
+		if !strings.Contains(errContent, "info="+info+"\n") {
+			t.Errorf("%q should be logged in error-test.log", info)
+		}
+	}
+}
+
+func TestUnknownLevel(t *testing.T) {
+	l, dir := newTestLogger(t, &LogConfig{UnknownLevelFallback: WarnLevel})
+	l.Log(Level(7), "TEST_OBJ", "unknown 1")
+	l.LogReq(Level(-5), "TEST_OBJ", "req-1", "unknown 2")
+	if cnt := l.UnknownLevelCount(); cnt != 2 {
+		t.Errorf("unknown level count is error: %d", cnt)
+	}
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	if n := strings.Count(content, "obj=ZLOG\tinfo=logLevel is error: Level(7), fallback to WARN\tcount=1\n"); n != 1 {
+		t.Errorf("warning should be logged once, got %d: %s", n, content)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		if !strings.Contains(line, "/level_test.go:") || !strings.Contains(line, "logLev=[WARN]") {
+			t.Errorf("line is error: %s", line)
+		}
+	}
+	for _, want := range []string{"info=unknown 1\n", "reqId=req-1\tinfo=unknown 2\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("%q not found: %s", want, content)
+		}
+	}
+
+	if _, err := New(&LogConfig{UnknownLevelFallback: Level(7)}); err == nil {
+		t.Error("invalid UnknownLevelFallback should return error")
+	}
+}
@@ -4,103 +4,341 @@
 // - Log* 开头的方法，用于打印通用日志
 // - LogReq* 开头的方法都会带有 reqId field，用于打印处理请求过程中产生的日志
 // - *Err 结尾的方法，用于打印类型为 error 的信息
+// - LogCtx* 开头的方法，从 context 中取出 reqId 及 fields（见 zlog.NewContext），适合在深层调用中打印请求相关日志
+// - *f 结尾的方法，info 按 fmt.Sprintf 格式化，日志级别未开启时不会执行格式化
+// - *w 结尾的方法，可以额外传入 Field（如 zlog.String、zlog.Int64），以 k=v 的形式追加在日志末尾
+// - *Since 结尾的方法，传入开始时间 time.Time 计算耗时（单调时钟），同时支持传入 Field
 //
 // 使用此日志库之前，必须先通过 zlog.Init() 方法进行初始化
+//
+// 包级别的方法都是对默认 Logger 的简单封装，默认 Logger 由 Init 创建，也可以通过 SetDefault 替换；
+// 如果需要多份独立的日志配置，可以通过 zlog.New() 创建新的 Logger
 
 package zlog
 
 import (
-	stdlog "log"
+	"context"
+	"errors"
+	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var (
-	logger    zlogger = nil
-	loggerMtx sync.Mutex
+	defaultLogger atomic.Value // *Logger
+	loggerMtx     sync.Mutex
 )
 
 func Init(conf *LogConfig) error {
 	loggerMtx.Lock()
 	defer loggerMtx.Unlock()
-	if logger != nil {
+	if Default() != nil {
 		panic("zlog cannot be initialized repeatedly!")
 	}
-	logConf := new(LogConfig)
-	logConf.Reset(conf)
-	logger = newZapLogger(logConf)
+	l, err := New(conf)
+	if err != nil {
+		return err
+	}
+	defaultLogger.Store(l)
 	return nil
 }
 
+// 返回默认 Logger，未初始化时返回 nil
+func Default() *Logger {
+	l, _ := defaultLogger.Load().(*Logger)
+	return l
+}
+
+// 替换默认 Logger，返回被替换掉的 Logger（可能为 nil），由调用方决定是否 Close
+func SetDefault(l *Logger) *Logger {
+	if l == nil {
+		panic("zlog default logger cannot be nil!")
+	}
+	loggerMtx.Lock()
+	defer loggerMtx.Unlock()
+	old := Default()
+	defaultLogger.Store(l)
+	return old
+}
+
+// 使用新的配置重新加载默认 Logger，见 Logger.Reload
+func Reload(conf *LogConfig) error {
+	l := Default()
+	if l == nil {
+		return errors.New("zlog is not initialized")
+	}
+	return l.Reload(conf)
+}
+
+// 调整默认 Logger 输出的日志级别，见 Logger.SetLevel
+func SetLevel(level Level) error {
+	return Default().SetLevel(level)
+}
+
+// 返回默认 Logger 输出的日志级别
+func GetLevel() Level {
+	return Default().GetLevel()
+}
+
+// 替换默认 Logger 按 obj 覆盖的日志级别，见 Logger.SetObjLevels
+func SetObjLevels(levels map[string]Level) error {
+	return Default().SetObjLevels(levels)
+}
+
+// 返回默认 Logger 按 obj 覆盖的日志级别
+func GetObjLevels() map[string]Level {
+	return Default().GetObjLevels()
+}
+
+// 返回默认 Logger 出现未知日志级别的次数
+func UnknownLevelCount() uint64 {
+	return Default().UnknownLevelCount()
+}
+
+// 返回用于查看、调整默认 Logger 日志级别的 http.Handler，见 Logger.LevelHandler
+func LevelHandler() http.Handler {
+	return Default().LevelHandler()
+}
+
+func logger() zlogger {
+	return Default().zl
+}
+
 func Sync() error {
-	return logger.Sync()
+	return logger().Sync()
 }
 
 func Close() error {
-	logger.Close()
-	stdlog.Println("[zlog] close")
+	logger().Close()
+	internalLog.Println("[zlog] close")
 	return nil
 }
 
+// 返回绑定了 fields 的默认 Logger 的子 Logger，见 Logger.With
+func With(fields ...Field) *Logger {
+	return Default().With(fields...)
+}
+
 // 记录服务启动耗时
 // startTimeNS 单位：纳秒
-func LogStart(logLevel, info string, startTimeNS int64) {
-	logger.LogStart(logLevel, info, startTimeNS)
+func LogStart(logLevel Level, info string, startTimeNS int64) {
+	logger().LogStart(logLevel, info, costSince(startTimeNS))
+}
+
+// 同 LogStart，fields 会以 k=v 的形式追加在日志末尾
+func LogStartw(logLevel Level, info string, startTimeNS int64, fields ...Field) {
+	logger().LogStart(logLevel, info, costSince(startTimeNS), fields...)
+}
+
+// 同 LogStartw，耗时由 time.Since(start) 计算，使用单调时钟，不受系统时间调整影响
+func LogStartSince(logLevel Level, info string, start time.Time, fields ...Field) {
+	logger().LogStart(logLevel, info, time.Since(start), fields...)
 }
 
-func Log(logLevel, obj, info string) {
-	logger.Log(logLevel, obj, info)
+func Log(logLevel Level, obj, info string) {
+	logger().Log(logLevel, obj, info)
+}
+
+// 同 Log，fields 会以 k=v 的形式追加在日志末尾
+func Logw(logLevel Level, obj, info string, fields ...Field) {
+	logger().Log(logLevel, obj, info, fields...)
 }
 
 // 用于打印离线数据， data=xxx
 // 如果 data 是 struct/map 最终会被 json.Marshal 成字符串
-func LogData(logLevel, obj string, data interface{}) {
-	logger.LogData(logLevel, obj, data)
+func LogData(logLevel Level, obj string, data interface{}) {
+	logger().LogData(logLevel, obj, data)
 }
 
-func LogErr(logLevel, obj, info string, err interface{}) {
-	logger.LogErr(logLevel, obj, info, err)
+// 同 LogData，fields 会以 k=v 的形式追加在日志末尾
+func LogDataw(logLevel Level, obj string, data interface{}, fields ...Field) {
+	logger().LogData(logLevel, obj, data, fields...)
+}
+
+func LogErr(logLevel Level, obj, info string, err interface{}) {
+	logger().LogErr(logLevel, obj, info, err)
+}
+
+// 同 LogErr，fields 会以 k=v 的形式追加在日志末尾
+func LogErrw(logLevel Level, obj, info string, err interface{}, fields ...Field) {
+	logger().LogErr(logLevel, obj, info, err, fields...)
 }
 
 // （不带reqId）请求了其他组件，比如 mysql、redis、cnd 等
-func LogThirdPart(logLevel, obj, host, info string, startTimeNS int64) {
-	logger.LogThirdPart(logLevel, obj, host, info, startTimeNS)
+func LogThirdPart(logLevel Level, obj, host, info string, startTimeNS int64) {
+	logger().LogThirdPart(logLevel, obj, host, info, costSince(startTimeNS))
+}
+
+// 同 LogThirdPart，fields 会以 k=v 的形式追加在日志末尾
+func LogThirdPartw(logLevel Level, obj, host, info string, startTimeNS int64, fields ...Field) {
+	logger().LogThirdPart(logLevel, obj, host, info, costSince(startTimeNS), fields...)
+}
+
+// 同 LogThirdPartw，耗时由 time.Since(start) 计算，使用单调时钟，不受系统时间调整影响
+func LogThirdPartSince(logLevel Level, obj, host, info string, start time.Time, fields ...Field) {
+	logger().LogThirdPart(logLevel, obj, host, info, time.Since(start), fields...)
 }
 
 // FATAL log and panic
 // 打印 FATAL 日志并触发 panic
 // 此方法可用于记录初始化失败
 func LogPanic(obj, info string, err interface{}) {
-	logger.LogPanic(obj, info, err)
+	logger().LogPanic(obj, info, err)
+}
+
+// 同 LogPanic，fields 会以 k=v 的形式追加在日志末尾
+func LogPanicw(obj, info string, err interface{}, fields ...Field) {
+	logger().LogPanic(obj, info, err, fields...)
+}
+
+// FATAL log and exit
+// 打印 FATAL 日志，同步刷新日志文件、执行通过 RegisterShutdownHook 注册的方法后退出进程
+// 退出码见 LogConfig.FatalExitCode
+func LogFatal(obj, info string, err interface{}) {
+	logger().LogFatal(obj, info, err)
 }
 
-func LogReq(logLevel, obj, reqId, info string) {
-	logger.LogReq(logLevel, obj, reqId, info)
+// 同 LogFatal，fields 会以 k=v 的形式追加在日志末尾
+func LogFatalw(obj, info string, err interface{}, fields ...Field) {
+	logger().LogFatal(obj, info, err, fields...)
+}
+
+func LogReq(logLevel Level, obj, reqId, info string) {
+	logger().LogReq(logLevel, obj, reqId, info)
+}
+
+// 同 LogReq，fields 会以 k=v 的形式追加在日志末尾
+func LogReqw(logLevel Level, obj, reqId, info string, fields ...Field) {
+	logger().LogReq(logLevel, obj, reqId, info, fields...)
 }
 
 // 用于打印离线数据， data=xxx
 // 如果 data 是 struct/map 最终会被 json.Marshal 成字符串
-func LogReqData(logLevel, obj, reqId string, data interface{}) {
-	logger.LogReqData(logLevel, obj, reqId, data)
+func LogReqData(logLevel Level, obj, reqId string, data interface{}) {
+	logger().LogReqData(logLevel, obj, reqId, data)
+}
+
+// 同 LogReqData，fields 会以 k=v 的形式追加在日志末尾
+func LogReqDataw(logLevel Level, obj, reqId string, data interface{}, fields ...Field) {
+	logger().LogReqData(logLevel, obj, reqId, data, fields...)
 }
 
-func LogReqErr(logLevel, obj, reqId, info string, err interface{}) {
-	logger.LogReqErr(logLevel, obj, reqId, info, err)
+func LogReqErr(logLevel Level, obj, reqId, info string, err interface{}) {
+	logger().LogReqErr(logLevel, obj, reqId, info, err)
+}
+
+// 同 LogReqErr，fields 会以 k=v 的形式追加在日志末尾
+func LogReqErrw(logLevel Level, obj, reqId, info string, err interface{}, fields ...Field) {
+	logger().LogReqErr(logLevel, obj, reqId, info, err, fields...)
 }
 
 // （带reqId）业务请求中，请求了其他组件，比如 mysql、redis、cnd 等
-func LogReqThirdPart(logLevel, obj, reqId, host, info string, startTimeNS int64) {
-	logger.LogReqThirdPart(logLevel, obj, reqId, host, info, startTimeNS)
+func LogReqThirdPart(logLevel Level, obj, reqId, host, info string, startTimeNS int64) {
+	logger().LogReqThirdPart(logLevel, obj, reqId, host, info, costSince(startTimeNS))
+}
+
+// 同 LogReqThirdPart，fields 会以 k=v 的形式追加在日志末尾
+func LogReqThirdPartw(logLevel Level, obj, reqId, host, info string, startTimeNS int64, fields ...Field) {
+	logger().LogReqThirdPart(logLevel, obj, reqId, host, info, costSince(startTimeNS), fields...)
+}
+
+// 同 LogReqThirdPartw，耗时由 time.Since(start) 计算，使用单调时钟，不受系统时间调整影响
+func LogReqThirdPartSince(logLevel Level, obj, reqId, host, info string, start time.Time, fields ...Field) {
+	logger().LogReqThirdPart(logLevel, obj, reqId, host, info, time.Since(start), fields...)
 }
 
 // 完整接收到请求数据之后，打印此日志
 // startTimeNS 指开始接受请求的时间
-func LogReqBegin(logLevel, reqId, reqClientIP, reqUri, reqParams string, startTimeNS int64) {
-	logger.LogReqBegin(logLevel, reqId, reqClientIP, reqUri, reqParams, startTimeNS)
+func LogReqBegin(logLevel Level, reqId, reqClientIP, reqUri, reqParams string, startTimeNS int64) {
+	logger().LogReqBegin(logLevel, reqId, reqClientIP, reqUri, reqParams, costSince(startTimeNS))
+}
+
+// 同 LogReqBegin，fields 会以 k=v 的形式追加在日志末尾
+func LogReqBeginw(logLevel Level, reqId, reqClientIP, reqUri, reqParams string, startTimeNS int64, fields ...Field) {
+	logger().LogReqBegin(logLevel, reqId, reqClientIP, reqUri, reqParams, costSince(startTimeNS), fields...)
+}
+
+// 同 LogReqBeginw，耗时由 time.Since(start) 计算，使用单调时钟，不受系统时间调整影响
+func LogReqBeginSince(logLevel Level, reqId, reqClientIP, reqUri, reqParams string, start time.Time, fields ...Field) {
+	logger().LogReqBegin(logLevel, reqId, reqClientIP, reqUri, reqParams, time.Since(start), fields...)
 }
 
 // 请求处理结束，打印此日志
 // startTimeNS 指开始接受请求的时间，同 LogReqBegin 中的 startTimeNS
 // retData 返回的数据
-func LogReqEnd(logLevel, reqId, retData string, startTimeNS int64) {
-	logger.LogReqEnd(logLevel, reqId, retData, startTimeNS)
+func LogReqEnd(logLevel Level, reqId, retData string, startTimeNS int64) {
+	logger().LogReqEnd(logLevel, reqId, retData, costSince(startTimeNS))
+}
+
+// 同 LogReqEnd，fields 会以 k=v 的形式追加在日志末尾
+func LogReqEndw(logLevel Level, reqId, retData string, startTimeNS int64, fields ...Field) {
+	logger().LogReqEnd(logLevel, reqId, retData, costSince(startTimeNS), fields...)
+}
+
+// 同 LogReqEndw，耗时由 time.Since(start) 计算，使用单调时钟，不受系统时间调整影响
+func LogReqEndSince(logLevel Level, reqId, retData string, start time.Time, fields ...Field) {
+	logger().LogReqEnd(logLevel, reqId, retData, time.Since(start), fields...)
+}
+
+// 请求处理结束，打印带有返回码的 RE 日志：obj=RE reqId reqHost retCode retSize retData cost
+// 日志级别由 retCode 决定（默认 4xx 为 WARN、5xx 为 ERROR，其他为 INFO），见 LogConfig.RetCodeLevel
+// retCode 可以是整数或字符串；reqHost 为空时不输出 reqHost，retSize 小于 0 时不输出 retSize
+func LogReqEndCode(reqId, reqHost string, retCode interface{}, retSize int, retData string, startTimeNS int64) {
+	zl := logger()
+	zl.LogReqEndCode(zl.retCodeLevel(retCode), reqId, reqHost, retCode, retSize, retData, costSince(startTimeNS))
+}
+
+// 同 LogReqEndCode，fields 会以 k=v 的形式追加在日志末尾
+func LogReqEndCodew(reqId, reqHost string, retCode interface{}, retSize int, retData string, startTimeNS int64, fields ...Field) {
+	zl := logger()
+	zl.LogReqEndCode(zl.retCodeLevel(retCode), reqId, reqHost, retCode, retSize, retData, costSince(startTimeNS), fields...)
+}
+
+// 同 LogReqEndCodew，耗时由 time.Since(start) 计算，使用单调时钟，不受系统时间调整影响
+func LogReqEndCodeSince(reqId, reqHost string, retCode interface{}, retSize int, retData string, start time.Time, fields ...Field) {
+	zl := logger()
+	zl.LogReqEndCode(zl.retCodeLevel(retCode), reqId, reqHost, retCode, retSize, retData, time.Since(start), fields...)
+}
+
+// 同 LogReqEndCodeSince，日志级别由 logLevel 指定，用于返回码不适用 LogConfig.RetCodeLevel 的场景，如 gRPC 的状态码
+func LogReqEndCodeLevel(logLevel Level, reqId, reqHost string, retCode interface{}, retSize int, retData string, start time.Time, fields ...Field) {
+	logger().LogReqEndCode(logLevel, reqId, reqHost, retCode, retSize, retData, time.Since(start), fields...)
+}
+
+// 从 ctx 中取出 reqId 及 fields（见 NewContext）打印日志，ctx 中没有 reqId 时不输出 reqId 字段
+func LogCtx(ctx context.Context, logLevel Level, obj, info string) {
+	logger().LogCtx(ctx, logLevel, obj, info)
+}
+
+// 用于打印离线数据， data=xxx
+// 如果 data 是 struct/map 最终会被 json.Marshal 成字符串
+func LogCtxData(ctx context.Context, logLevel Level, obj string, data interface{}) {
+	logger().LogCtxData(ctx, logLevel, obj, data)
+}
+
+func LogCtxErr(ctx context.Context, logLevel Level, obj, info string, err interface{}) {
+	logger().LogCtxErr(ctx, logLevel, obj, info, err)
+}
+
+// 业务请求中，请求了其他组件，比如 mysql、redis、cnd 等
+func LogCtxThirdPart(ctx context.Context, logLevel Level, obj, host, info string, startTimeNS int64) {
+	logger().LogCtxThirdPart(ctx, logLevel, obj, host, info, costSince(startTimeNS))
+}
+
+// info 按 fmt.Sprintf(format, args...) 格式化，logLevel 未开启时不会执行格式化
+func Logf(logLevel Level, obj, format string, args ...interface{}) {
+	logger().Logf(logLevel, obj, format, args...)
+}
+
+func LogErrf(logLevel Level, obj string, err interface{}, format string, args ...interface{}) {
+	logger().LogErrf(logLevel, obj, err, format, args...)
+}
+
+func LogReqf(logLevel Level, obj, reqId, format string, args ...interface{}) {
+	logger().LogReqf(logLevel, obj, reqId, format, args...)
+}
+
+func LogReqErrf(logLevel Level, obj, reqId string, err interface{}, format string, args ...interface{}) {
+	logger().LogReqErrf(logLevel, obj, reqId, err, format, args...)
 }
@@ -7,20 +7,34 @@ import (
 )
 
 var (
-	defaultMaxLogLevel   int8 = 0
-	defaultMaxLogSizeMB  int  = 1024
-	defaultMaxLogFileNum int  = 10
-	defaultLogDirName         = flag.String("log_dir", "", "default log file dir")
-	defaultLogFileName        = filepath.Base(os.Args[0]) + ".log"
+	defaultMaxLogLevel   Level = InfoLevel
+	defaultMaxLogSizeMB  int   = 1024
+	defaultMaxLogFileNum int   = 10
+	defaultFatalExitCode int   = 1
+	defaultLogDirName          = flag.String("log_dir", "", "default log file dir")
+	defaultLogFileName         = filepath.Base(os.Args[0]) + ".log"
 )
 
 type LogConfig struct {
-	MaxLogLevel      int8   `json:"MaxLogLevel"`      // 输出的日志级别
-	MaxLogSizeMB     int    `json:"MaxLogSizeMB"`     // 单个日志文件大小
-	MaxLogFileNum    int    `json:"MaxLogFileNum"`    // 保留日志文件个数
-	LogDirName       string `json:"LogDirName"`       // 日志输出目录
-	LogFileName      string `json:"LogFileName"`      // 日志文件名（内容包含各个 level 的日志）
-	ErrorLogFileName string `json:"ErrorLogFileName"` // 错误日志文件名（内容那个包含 ERROR/FATAL 日志）
+	MaxLogLevel          Level  `json:"MaxLogLevel"`          // 输出的日志级别，支持数值或级别名，如 -1 / "DEBUG"
+	MaxLogSizeMB         int    `json:"MaxLogSizeMB"`         // 单个日志文件大小
+	MaxLogFileNum        int    `json:"MaxLogFileNum"`        // 保留日志文件个数
+	LogDirName           string `json:"LogDirName"`           // 日志输出目录
+	LogFileName          string `json:"LogFileName"`          // 日志文件名（内容包含各个 level 的日志）
+	ErrorLogFileName     string `json:"ErrorLogFileName"`     // 错误日志文件名（内容那个包含 ERROR/FATAL 日志）
+	FatalExitCode        int    `json:"FatalExitCode"`        // LogFatal 退出进程时的退出码，默认为 1
+	UnknownLevelFallback Level  `json:"UnknownLevelFallback"` // 遇到未知的日志级别时，按此级别输出，默认为 INFO
+	StackLevel           *Level `json:"StackLevel"`           // 该级别及以上的日志记录堆栈（stack=xxx），如 "ERROR"；不设置（nil）时不记录
+	StackDepth           int    `json:"StackDepth"`           // 堆栈最多记录的层数，默认为 32
+	StackSkip            int    `json:"StackSkip"`            // 堆栈额外跳过的层数，适用于对 zlog 再次封装的场景，小于 0 时按 0 处理
+	CostUnit             string `json:"CostUnit"`             // 耗时（cost）的输出单位：ms（默认）/ us / ns / float-ms
+
+	// 按 obj 覆盖 MaxLogLevel，key 为 obj，支持以 * 结尾的前缀匹配，如 {"MYSQL*": "WARN"}，只作用于 program.log
+	ObjLogLevel map[string]Level `json:"ObjLogLevel"`
+
+	// LogReqEndCode 按返回码选择日志级别，key 为完整的返回码（如 "404"）或分类（如 "5xx"），未匹配的为 INFO
+	// 不设置（nil）时默认为 {"4xx": "WARN", "5xx": "ERROR"}；设置后整体替换默认值，设置为空（如 {}）时不按返回码区分，均为 INFO
+	RetCodeLevel map[string]Level `json:"RetCodeLevel"`
 }
 
 func (this *LogConfig) Reset(conf *LogConfig) {
@@ -53,6 +67,48 @@ func (this *LogConfig) Reset(conf *LogConfig) {
 	if conf.ErrorLogFileName != "" {
 		this.ErrorLogFileName = conf.ErrorLogFileName
 	}
+
+	this.FatalExitCode = defaultFatalExitCode
+	if conf.FatalExitCode != 0 {
+		this.FatalExitCode = conf.FatalExitCode
+	}
+
+	this.UnknownLevelFallback = conf.UnknownLevelFallback
+
+	this.StackLevel = nil
+	if conf.StackLevel != nil {
+		stackLevel := *conf.StackLevel
+		this.StackLevel = &stackLevel
+	}
+	this.StackDepth = defaultStackDepth
+	if conf.StackDepth > 0 {
+		this.StackDepth = conf.StackDepth
+	}
+	this.StackSkip = 0
+	if conf.StackSkip > 0 {
+		this.StackSkip = conf.StackSkip
+	}
+
+	this.CostUnit = COST_UNIT_MS
+	if conf.CostUnit != "" {
+		this.CostUnit = conf.CostUnit
+	}
+
+	this.ObjLogLevel = nil
+	if len(conf.ObjLogLevel) != 0 {
+		this.ObjLogLevel = make(map[string]Level, len(conf.ObjLogLevel))
+		for obj, level := range conf.ObjLogLevel {
+			this.ObjLogLevel[obj] = level
+		}
+	}
+
+	this.RetCodeLevel = defaultRetCodeLevel()
+	if conf.RetCodeLevel != nil {
+		this.RetCodeLevel = make(map[string]Level, len(conf.RetCodeLevel))
+		for code, level := range conf.RetCodeLevel {
+			this.RetCodeLevel[code] = level
+		}
+	}
 }
 
 func (this *LogConfig) GetLogFilePath() string {
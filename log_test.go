@@ -3,8 +3,19 @@ package zlog
 // go test -bench .
 
 import (
+	"context"
 	"errors"
+	"io/ioutil"
+	stdlog "log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -36,6 +47,619 @@ func TestLog(t *testing.T) {
 	LogThirdPart(LL_INFO, testObj, "127.0.0.1:80", "", 1)
 }
 
+// newTestLogger 在临时目录中创建一个独立的 Logger，返回 Logger 及日志目录
+func newTestLogger(t *testing.T, conf *LogConfig) (*Logger, string) {
+	dir, err := ioutil.TempDir("", "zlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if conf == nil {
+		conf = new(LogConfig)
+	}
+	conf.LogDirName = dir
+	conf.LogFileName = "test.log"
+	l, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, dir
+}
+
+func readLogFile(t *testing.T, dir, name string) string {
+	bs, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return string(bs)
+}
+
+func TestNew(t *testing.T) {
+	l, dir := newTestLogger(t, nil)
+	l.Log(LL_INFO, "TEST_OBJ", "is ok")
+	l.LogErr(LL_ERROR, "TEST_OBJ", "get version", errors.New("time out"))
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	if !strings.Contains(content, "/log_test.go:") {
+		t.Errorf("caller is error: %s", content)
+	}
+	if !strings.Contains(content, "obj=TEST_OBJ\tinfo=is ok") {
+		t.Errorf("info log not found: %s", content)
+	}
+	errContent := readLogFile(t, dir, "error-test.log")
+	if strings.Contains(errContent, "is ok") || !strings.Contains(errContent, "err=time out") {
+		t.Errorf("error log is error: %s", errContent)
+	}
+
+	if _, err := New(&LogConfig{MaxLogLevel: 5}); err == nil {
+		t.Error("invalid MaxLogLevel should return error")
+	}
+}
+
+func TestLogw(t *testing.T) {
+	l, dir := newTestLogger(t, nil)
+	l.Logw(LL_INFO, "TEST_OBJ", "is ok",
+		String("module", "cache"),
+		Int64("shard", 3),
+		Duration("timeout", 1500*time.Microsecond),
+		Err(errors.New("time out")),
+		Err(nil),
+	)
+	l.LogReqErrw(LL_ERROR, "TEST_OBJ", "req-1", "get version", "fail", Any("data", map[string]int{"k": 1}))
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	if !strings.Contains(content, "obj=TEST_OBJ\tinfo=is ok\tmodule=cache\tshard=3\ttimeout=1.5ms\terr=time out\n") {
+		t.Errorf("fields log is error: %s", content)
+	}
+	if !strings.Contains(content, "reqId=req-1\tinfo=get version\terr=fail\tdata={\"k\":1}\n") {
+		t.Errorf("fields log is error: %s", content)
+	}
+}
+
+func TestWith(t *testing.T) {
+	l, dir := newTestLogger(t, nil)
+	child := l.With(String("module", "cache"), Int("shard", 3))
+	child.With(String("tenant", "t1")).Log(LL_ERROR, "TEST_OBJ", "grandchild")
+	child.Logw(LL_INFO, "TEST_OBJ", "child", Int64("n", 1))
+	child.LogReqw(LL_INFO, "TEST_OBJ", "req-1", "child req", Int64("n", 2))
+	child.LogCtx(NewContext(context.Background(), "req-2", String("tenant", "t2")), LL_INFO, "TEST_OBJ", "child ctx")
+	child.Close()
+	l.Log(LL_INFO, "TEST_OBJ", "parent")
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	for _, want := range []string{
+		"info=grandchild\tmodule=cache\tshard=3\ttenant=t1\n",
+		"info=child\tmodule=cache\tshard=3\tn=1\n",
+		"reqId=req-1\tinfo=child req\tmodule=cache\tshard=3\tn=2\n",
+		"reqId=req-2\tinfo=child ctx\tmodule=cache\tshard=3\ttenant=t2\n",
+		"info=parent\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("%q not found: %s", want, content)
+		}
+	}
+	errContent := readLogFile(t, dir, "error-test.log")
+	if !strings.Contains(errContent, "info=grandchild\tmodule=cache\tshard=3\ttenant=t1\n") {
+		t.Errorf("error log is error: %s", errContent)
+	}
+}
+
+func TestLogCtx(t *testing.T) {
+	l, dir := newTestLogger(t, nil)
+	ctx := NewContext(context.Background(), "req-1", String("module", "cache"))
+	ctx = NewContext(ctx, "", Int("shard", 3))
+	if reqId := ReqIdFromContext(ctx); reqId != "req-1" {
+		t.Errorf("reqId is error: %s", reqId)
+	}
+	l.LogCtx(ctx, LL_INFO, "TEST_OBJ", "with ctx")
+	l.LogCtxErr(ctx, LL_ERROR, "TEST_OBJ", "get version", "fail")
+	l.LogCtx(context.Background(), LL_INFO, "TEST_OBJ", "without ctx")
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	for _, want := range []string{
+		"/log_test.go:",
+		"obj=TEST_OBJ\treqId=req-1\tinfo=with ctx\tmodule=cache\tshard=3\n",
+		"obj=TEST_OBJ\treqId=req-1\tinfo=get version\terr=fail\tmodule=cache\tshard=3\n",
+		"obj=TEST_OBJ\tinfo=without ctx\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("%q not found: %s", want, content)
+		}
+	}
+}
+
+type countStringer int
+
+func (this *countStringer) String() string {
+	*this++
+	return "formatted"
+}
+
+func TestLogf(t *testing.T) {
+	l, dir := newTestLogger(t, nil)
+	var cnt countStringer
+	l.Logf(LL_DEBUG, "TEST_OBJ", "debug %s", &cnt)
+	l.Logf(LL_INFO, "TEST_OBJ", "info %s %d", &cnt, 1)
+	l.LogReqErrf(LL_ERROR, "TEST_OBJ", "req-1", "fail", "get %s", "version")
+	l.Close()
+
+	if cnt != 1 {
+		t.Errorf("disabled level should not format, format count: %d", cnt)
+	}
+	content := readLogFile(t, dir, "test.log")
+	for _, want := range []string{
+		"obj=TEST_OBJ\tinfo=info formatted 1\n",
+		"obj=TEST_OBJ\treqId=req-1\tinfo=get version\terr=fail\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("%q not found: %s", want, content)
+		}
+	}
+	if strings.Contains(content, "debug") {
+		t.Errorf("debug log should be disabled: %s", content)
+	}
+}
+
+func TestReload(t *testing.T) {
+	l, dir := newTestLogger(t, nil)
+	child := l.With(String("module", "cache"))
+
+	const goroutineNum, logNum = 4, 2000
+	var wg sync.WaitGroup
+	for i := 0; i < goroutineNum; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < logNum; j++ {
+				child.Logw(LL_INFO, "TEST_OBJ", "reload", Int("g", i), Int("n", j))
+			}
+		}(i)
+	}
+	time.Sleep(time.Millisecond)
+	err := l.Reload(&LogConfig{LogDirName: dir, LogFileName: "reload.log", MaxLogLevel: DebugLevel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	child.Log(LL_DEBUG, "TEST_OBJ", "after reload")
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log") + readLogFile(t, dir, "reload.log")
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		if !strings.Contains(line, "info=reload") {
+			continue
+		}
+		idx := strings.Index(line, "\tg=")
+		if seen[line[idx:]] {
+			t.Errorf("duplicate line: %s", line)
+		}
+		seen[line[idx:]] = true
+	}
+	if len(seen) != goroutineNum*logNum {
+		t.Errorf("lost lines: want %d, got %d", goroutineNum*logNum, len(seen))
+	}
+	if !strings.Contains(readLogFile(t, dir, "reload.log"), "info=after reload\tmodule=cache") {
+		t.Error("new config is not applied")
+	}
+
+	if err := l.Reload(&LogConfig{MaxLogLevel: 5}); err == nil {
+		t.Error("invalid MaxLogLevel should return error")
+	}
+}
+
+func TestLogFatal(t *testing.T) {
+	exitCode := 0
+	defer SetExitFunc(func(code int) { exitCode = code })()
+	t.Cleanup(resetShutdownHooks())
+	var hooks []int
+	RegisterShutdownHook(func() { hooks = append(hooks, 1) })
+	RegisterShutdownHook(func() { hooks = append(hooks, 2); panic("hook panic") })
+
+	l, dir := newTestLogger(t, &LogConfig{MaxLogLevel: FatalLevel, FatalExitCode: 3})
+	defer l.Close()
+	l.LogFatal("TEST_OBJ", "init failed", "boom")
+
+	if exitCode != 3 {
+		t.Errorf("exit code is error: %d", exitCode)
+	}
+	if len(hooks) != 2 || hooks[0] != 2 || hooks[1] != 1 {
+		t.Errorf("shutdown hooks is error: %v", hooks)
+	}
+	// flushed without Close
+	want := "logLev=[FATAL]\t\tobj=TEST_OBJ\tinfo=init failed\terr=boom\n"
+	for _, name := range []string{"test.log", "error-test.log"} {
+		content := readLogFile(t, dir, name)
+		if !strings.Contains(content, want) || !strings.Contains(content, "/log_test.go:") {
+			t.Errorf("%s is error: %s", name, content)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	l, dir := newTestLogger(t, &LogConfig{MaxLogLevel: FatalLevel})
+	defer l.Close()
+
+	var recovered interface{}
+	func() {
+		defer l.RecoverReq("TEST_OBJ", "req-1", OnPanic(func(r interface{}) { recovered = r }))
+		var m map[string]int
+		m["k"] = 1 // panic here
+	}()
+	if recovered == nil {
+		t.Error("callback is not called")
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "re-panic" {
+				t.Errorf("should re-panic, got %v", r)
+			}
+		}()
+		defer l.Recover("TEST_OBJ", RePanic())
+		panic("re-panic")
+	}()
+
+	for _, name := range []string{"test.log", "error-test.log"} {
+		lines := strings.Split(strings.TrimSpace(readLogFile(t, dir, name)), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("%s should have 2 lines: %v", name, lines)
+		}
+		for _, line := range lines {
+			if !strings.Contains(line, "logLev=[FATAL]\t\tobj=TEST_OBJ\t") ||
+				!strings.Contains(line, "/log_test.go:") ||
+				!strings.Contains(line, "\tstack=github.com/fevin/zlog.TestRecover.func") {
+				t.Errorf("%s is error: %s", name, line)
+			}
+		}
+		if !strings.Contains(lines[0], "reqId=req-1\tinfo=recover from panic\terr=assignment to entry in nil map") {
+			t.Errorf("%s is error: %s", name, lines[0])
+		}
+		if !strings.Contains(lines[1], "info=recover from panic\terr=re-panic") {
+			t.Errorf("%s is error: %s", name, lines[1])
+		}
+	}
+}
+
+func TestStack(t *testing.T) {
+	stackLevel := ErrorLevel
+	l, dir := newTestLogger(t, &LogConfig{StackLevel: &stackLevel, StackDepth: 2})
+	l.Log(LL_WARN, "TEST_OBJ", "no stack")
+	l.With(String("module", "cache")).LogErr(LL_ERROR, "TEST_OBJ", "with stack", "fail")
+	func() {
+		defer func() { recover() }()
+		l.LogPanic("TEST_OBJ", "panic with stack", "fail")
+	}()
+	l.Close()
+
+	lines := strings.Split(strings.TrimSpace(readLogFile(t, dir, "test.log")), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("log should have 3 lines: %v", lines)
+	}
+	if strings.Contains(lines[0], "stack=") {
+		t.Errorf("WARN log should not have stack: %s", lines[0])
+	}
+	// With 绑定的字段位于 stack 之前
+	if !strings.Contains(lines[1], "\terr=fail\tmodule=cache\tstack=") {
+		t.Errorf("with fields should be written before stack: %s", lines[1])
+	}
+	for i, fn := range []string{"TestStack", "TestStack.func1"} {
+		line := lines[i+1]
+		idx := strings.Index(line, "\tstack=")
+		if idx < 0 {
+			t.Errorf("log should have stack: %s", line)
+			continue
+		}
+		stack := line[idx+len("\tstack="):]
+		if !strings.HasPrefix(stack, "github.com/fevin/zlog."+fn+"\\n\\t") || strings.Count(stack, "\\n\\t") != 2 {
+			t.Errorf("stack is error: %s", stack)
+		}
+	}
+}
+
+// StackSkip 小于 0 时按 0 处理，堆栈中不包含 zlog 自身
+func TestStackNegativeSkip(t *testing.T) {
+	stackLevel := ErrorLevel
+	l, dir := newTestLogger(t, &LogConfig{StackLevel: &stackLevel, StackDepth: 1, StackSkip: -3})
+	l.Log(LL_ERROR, "TEST_OBJ", "with stack")
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	if !strings.Contains(content, "\tstack=github.com/fevin/zlog.TestStackNegativeSkip\\n\\t") {
+		t.Errorf("stack should start from the caller: %s", content)
+	}
+}
+
+func TestStackInfoLevel(t *testing.T) {
+	stackLevel := InfoLevel
+	l, dir := newTestLogger(t, &LogConfig{StackLevel: &stackLevel, StackDepth: 1})
+	l.Log(LL_DEBUG, "TEST_OBJ", "no stack")
+	l.Log(LL_INFO, "TEST_OBJ", "with stack")
+	l.Close()
+
+	lines := strings.Split(strings.TrimSpace(readLogFile(t, dir, "test.log")), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("log should have 1 line: %v", lines)
+	}
+	if !strings.Contains(lines[0], "\tstack=github.com/fevin/zlog.TestStackInfoLevel") {
+		t.Errorf("INFO log should have stack: %s", lines[0])
+	}
+}
+
+func TestTimer(t *testing.T) {
+	l, dir := newTestLogger(t, nil)
+	timer := l.StartTimer("MYSQL", "127.0.0.1:3306")
+	time.Sleep(2 * time.Millisecond)
+	timer.Lap("query")
+	timer.Lap("parse")
+	timer.EndErr(LL_WARN, "select user", "timeout")
+	func() {
+		defer l.StartTimer("CACHE", "").End(LL_INFO, "get user")
+	}()
+	l.StartTimer("CACHE", "").EndErr(LL_WARN, "set user", "timeout")
+	child := l.With(String("module", "cache"))
+	child.StartTimer("CACHE", "").EndErr(LL_WARN, "del user", "timeout")
+	child.StartTimer("CACHE", "127.0.0.1:6379").EndErr(LL_WARN, "del user", "timeout")
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("log should have 5 lines: %s", content)
+	}
+	if !strings.Contains(lines[0], "/log_test.go:") ||
+		!strings.Contains(lines[0], "obj=MYSQL\thost=127.0.0.1:3306\tinfo=select user\tcost=") ||
+		!strings.Contains(lines[0], "\terr=timeout\tquery=") ||
+		!strings.Contains(lines[0], "\tparse=") ||
+		strings.Contains(lines[0], "query=0\t") {
+		t.Errorf("timer log is error: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "/log_test.go:") || !strings.Contains(lines[1], "obj=CACHE\tinfo=get user\tcost=") {
+		t.Errorf("timer log is error: %s", lines[1])
+	}
+	// 与带有 host 时相同，err 位于 cost 之后
+	if !strings.Contains(lines[2], "obj=CACHE\tinfo=set user\tcost=") || !strings.HasSuffix(lines[2], "\terr=timeout") {
+		t.Errorf("timer log is error: %s", lines[2])
+	}
+	// 有无 host 时，cost、err 都位于 With 绑定的字段之前
+	for _, line := range lines[3:] {
+		if !strings.Contains(line, "info=del user\tcost=") || !strings.HasSuffix(line, "\terr=timeout\tmodule=cache") {
+			t.Errorf("timer log is error: %s", line)
+		}
+	}
+}
+
+func TestCostUnit(t *testing.T) {
+	l, dir := newTestLogger(t, &LogConfig{CostUnit: COST_UNIT_US})
+	l.LogThirdPartw(LL_INFO, "REDIS", "127.0.0.1:6379", "get", 0)
+	l.LogThirdPartSince(LL_INFO, "REDIS", "127.0.0.1:6379", "get", time.Now().Add(-1500*time.Microsecond))
+	l.Logw(LL_INFO, "TEST_OBJ", "clock jump", Cost(-5*time.Millisecond))
+	l.Close()
+
+	lines := strings.Split(strings.TrimSpace(readLogFile(t, dir, "test.log")), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("log should have 3 lines: %v", lines)
+	}
+	if !strings.HasSuffix(lines[0], "\tcost=0") {
+		t.Errorf("zero start time should log cost=0: %s", lines[0])
+	}
+	var cost int64
+	if i := strings.Index(lines[1], "\tcost="); i < 0 {
+		t.Errorf("cost not found: %s", lines[1])
+	} else if cost, _ = strconv.ParseInt(lines[1][i+len("\tcost="):], 10, 64); cost < 1500 {
+		t.Errorf("cost should be at least 1500us: %s", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], "\tcost=0\tcostNegative=-5000") {
+		t.Errorf("negative cost is error: %s", lines[2])
+	}
+
+	l, dir = newTestLogger(t, &LogConfig{CostUnit: COST_UNIT_FLOAT_MS})
+	l.Logw(LL_INFO, "TEST_OBJ", "float", Cost(1234567*time.Nanosecond))
+	l.Close()
+	if content := readLogFile(t, dir, "test.log"); !strings.Contains(content, "\tcost=1.235\n") {
+		t.Errorf("float-ms cost is error: %s", content)
+	}
+
+	if _, err := New(&LogConfig{CostUnit: "s"}); err == nil {
+		t.Error("unknown cost unit should return error")
+	}
+}
+
+func TestRequest(t *testing.T) {
+	l, dir := newTestLogger(t, nil)
+	r := l.BeginRequest("req-1", "10.0.0.1", "/user", "id=1")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.Log(LL_INFO, "USER", "get user", Int("worker", i))
+			r.LogThirdPart(LL_INFO, "MYSQL", "127.0.0.1:3306", "select user", time.Now())
+			r.End(0, "ok")
+		}(i)
+	}
+	wg.Wait()
+	r.End(1, "again")
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	if len(lines) != 10 {
+		t.Fatalf("log should have 10 lines: %s", content)
+	}
+	if !strings.Contains(lines[0], "/log_test.go:") ||
+		!strings.Contains(lines[0], "obj=RB\treqId=req-1\treqClientIP=10.0.0.1\treqUri=/user\treqParams=id=1\tcost=0") {
+		t.Errorf("RB log is error: %s", lines[0])
+	}
+	if strings.Count(content, "obj=RE\t") != 1 || !regexp.MustCompile(`obj=RE\treqId=req-1\tretCode=0\tretData=ok\tcost=\d+\n`).MatchString(content) {
+		t.Errorf("RE log is error: %s", content)
+	}
+	if strings.Count(content, "reqId=req-1") != 10 || strings.Count(content, "obj=MYSQL\treqId=req-1\thost=127.0.0.1:3306") != 4 {
+		t.Errorf("request log is error: %s", content)
+	}
+}
+
+func TestLogReqEndCode(t *testing.T) {
+	l, dir := newTestLogger(t, nil)
+	l.LogReqEnd(LL_INFO, "req-1", "ok", 0)
+	l.LogReqEndCode("req-2", "api.example.com", 200, 12, "ok", 0)
+	l.LogReqEndCodew("req-3", "", 404, -1, "not found", 0, String("uid", "1"))
+	l.LogReqEndCodeSince("req-4", "", "503", 0, "busy", time.Now())
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	for _, expected := range []string{
+		"logLev=[INFO]\t\tobj=RE\treqId=req-1\tretData=ok\tcost=0\n",
+		"logLev=[INFO]\t\tobj=RE\treqId=req-2\treqHost=api.example.com\tretCode=200\tretSize=12\tretData=ok\tcost=0\n",
+		"logLev=[WARN]\t\tobj=RE\treqId=req-3\tretCode=404\tretData=not found\tcost=0\tuid=1\n",
+		"logLev=[ERROR]\t\tobj=RE\treqId=req-4\tretCode=503\tretSize=0\tretData=busy\tcost=0\n",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("RE log not found: %q in %s", expected, content)
+		}
+	}
+	if !strings.Contains(content, "/log_test.go:") {
+		t.Errorf("caller is error: %s", content)
+	}
+
+	l, dir = newTestLogger(t, &LogConfig{RetCodeLevel: map[string]Level{"404": InfoLevel, "4xx": ErrorLevel, "USER_LOCKED": WarnLevel}})
+	l.LogReqEndCode("req-1", "", 404, -1, "not found", 0)
+	l.LogReqEndCode("req-2", "", 403, -1, "forbidden", 0)
+	l.LogReqEndCode("req-3", "", "USER_LOCKED", -1, "locked", 0)
+	l.LogReqEndCode("req-4", "", 500, -1, "error", 0)
+	l.Close()
+	content = readLogFile(t, dir, "test.log")
+	for _, expected := range []string{
+		"logLev=[INFO]\t\tobj=RE\treqId=req-1\t",
+		"logLev=[ERROR]\t\tobj=RE\treqId=req-2\t",
+		"logLev=[WARN]\t\tobj=RE\treqId=req-3\t",
+		"logLev=[INFO]\t\tobj=RE\treqId=req-4\t",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("RE log not found: %q in %s", expected, content)
+		}
+	}
+
+	// 设置为空时不按返回码区分
+	l, dir = newTestLogger(t, &LogConfig{RetCodeLevel: map[string]Level{}})
+	l.LogReqEndCode("req-1", "", 404, -1, "not found", 0)
+	l.LogReqEndCode("req-2", "", 503, -1, "busy", 0)
+	l.Close()
+	content = readLogFile(t, dir, "test.log")
+	if strings.Count(content, "logLev=[INFO]\t\tobj=RE\t") != 2 {
+		t.Errorf("RE log should be INFO without classification: %s", content)
+	}
+
+	if _, err := New(&LogConfig{RetCodeLevel: map[string]Level{"5xx": Level(10)}}); err == nil {
+		t.Error("invalid retCode level should return error")
+	}
+}
+
+func TestRetCodeLevelKinds(t *testing.T) {
+	type statusCode uint16
+	levels, err := newRetCodeLevels(defaultRetCodeLevel())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, retCode := range []interface{}{uint(503), uint8(200), uint16(503), int16(503), int8(-1), uint64(503), statusCode(503), "503"} {
+		want := ErrorLevel
+		if retCode == uint8(200) || retCode == int8(-1) {
+			want = InfoLevel
+		}
+		if level := levels.level(retCode); level != want {
+			t.Errorf("level of %T(%v) should be %s, got %s", retCode, retCode, want, level)
+		}
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	l, dir := newTestLogger(t, nil)
+	restore := l.RedirectStdLog(LL_WARN, "STDLOG")
+	stdlog.Println("hello")
+	stdlog.SetFlags(stdlog.LstdFlags | stdlog.Lmicroseconds | stdlog.Lshortfile)
+	stdlog.SetPrefix("[lib] ")
+	stdlog.Printf("first\nsecond")
+	restore()
+	restore()
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("log should have 3 lines: %s", content)
+	}
+	for i, info := range []string{"hello", "first", "second"} {
+		if !strings.Contains(lines[i], "/log_test.go:") || !strings.HasSuffix(lines[i], "logLev=[WARN]\t\tobj=STDLOG\tinfo="+info) {
+			t.Errorf("std log is error: %s", lines[i])
+		}
+	}
+	if stdlog.Flags() != stdlog.LstdFlags || stdlog.Prefix() != "" || stdlog.Writer() != os.Stderr {
+		t.Errorf("std log should be restored: flags=%d prefix=%s", stdlog.Flags(), stdlog.Prefix())
+	}
+}
+
+func TestTrimStdLogHeader(t *testing.T) {
+	cases := []struct {
+		flags    int
+		prefix   string
+		line     string
+		expected string
+		file     string
+		lineNo   int
+	}{
+		{0, "", "msg", "msg", "", 0},
+		{stdlog.LstdFlags, "", "2009/01/23 01:23:23 msg", "msg", "", 0},
+		{stdlog.LstdFlags | stdlog.Lmicroseconds, "[p] ", "[p] 2009/01/23 01:23:23.123123 msg", "msg", "", 0},
+		{stdlog.Ltime | stdlog.Llongfile, "", "01:23:23 /a/b/c/d.go:23: msg: detail", "msg: detail", "/a/b/c/d.go", 23},
+		{stdlog.Lshortfile | stdlog.Lmsgprefix, "[p] ", "d.go:23: [p] msg", "msg", "d.go", 23},
+	}
+	for _, c := range cases {
+		info, file, lineNo := trimStdLogHeader(c.line, c.flags, c.prefix)
+		if info != c.expected || file != c.file || lineNo != c.lineNo {
+			t.Errorf("trimStdLogHeader(%q) should be %q %s:%d, got %q %s:%d", c.line, c.expected, c.file, c.lineNo, info, file, lineNo)
+		}
+	}
+}
+
+// 封装标准库 log 的方法，通过 calldepth 跳过自身
+func stdLogWrapper(msg string) {
+	stdlog.Output(2, msg)
+}
+
+func TestRedirectStdLogCallDepth(t *testing.T) {
+	l, dir := newTestLogger(t, nil)
+	restore := l.RedirectStdLog(LL_INFO, "STDLOG")
+	_, _, line, _ := runtime.Caller(0)
+	stdLogWrapper("wrapped")
+	stdlog.SetFlags(stdlog.Lshortfile)
+	stdLogWrapper("wrapped short")
+	restore()
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	for _, expected := range []string{
+		"/log_test.go:" + strconv.Itoa(line+1) + "\tlogLev=[INFO]\t\tobj=STDLOG\tinfo=wrapped\n",
+		"/log_test.go:" + strconv.Itoa(line+3) + "\tlogLev=[INFO]\t\tobj=STDLOG\tinfo=wrapped short\n",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("std log caller is error: %q not found in %s", expected, content)
+		}
+	}
+}
+
+func BenchmarkWithLog(b *testing.B) {
+	defer Sync()
+	l := With(String("module", "cache"), Int("shard", 3))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Log(LL_INFO, "test", "is ok")
+	}
+}
+
 func BenchmarkInfoLog(b *testing.B) {
 	defer Sync()
 	b.ResetTimer()
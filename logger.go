@@ -1,22 +1,381 @@
 package zlog
 
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
 type zlogger interface {
 	Sync() error
 	Close() error
+	With(fields ...Field) zlogger
+	Reload(logConf *LogConfig) error
+	SetLevel(level Level)
+	GetLevel() Level
+	SetObjLevels(levels map[string]Level) error
+	GetObjLevels() map[string]Level
+	UnknownLevelCount() uint64
+	enabled(logLevel Level, obj string) bool
+	levelEnabled(logLevel Level) bool
+	retCodeLevel(retCode interface{}) Level
+	zapCore() zapcore.Core
+	logBase(logLevel Level, obj string, base, fields []Field)
 
 	// Log*
-	LogStart(logLevel, info string, startTimeNS int64)
-	Log(logLevel, obj, info string)
-	LogData(logLevel, obj string, data interface{})
-	LogErr(logLevel, obj, info string, err interface{})
-	LogThirdPart(logLevel, obj, host, info string, startTimeNS int64)
-	LogPanic(obj, info string, err interface{})
+	LogStart(logLevel Level, info string, cost time.Duration, fields ...Field)
+	Log(logLevel Level, obj, info string, fields ...Field)
+	LogData(logLevel Level, obj string, data interface{}, fields ...Field)
+	LogErr(logLevel Level, obj, info string, err interface{}, fields ...Field)
+	LogThirdPart(logLevel Level, obj, host, info string, cost time.Duration, fields ...Field)
+	LogPanic(obj, info string, err interface{}, fields ...Field)
+	LogFatal(obj, info string, err interface{}, fields ...Field)
+	logRecovered(obj, reqId string, r interface{})
+	logCaller(logLevel Level, obj string, ent zapcore.Entry, fields ...Field)
 
 	// LogReq*
-	LogReq(logLevel, obj, reqId, info string)
-	LogReqData(logLevel, obj, reqId string, data interface{})
-	LogReqErr(logLevel, obj, reqId, info string, err interface{})
-	LogReqThirdPart(logLevel, obj, reqId, host, info string, startTimeNS int64)
-	LogReqBegin(logLevel, reqId, reqClientIP, reqUri, reqParams string, startTimeNS int64)
-	LogReqEnd(logLevel, reqId, retData string, startTimeNS int64)
+	LogReq(logLevel Level, obj, reqId, info string, fields ...Field)
+	LogReqData(logLevel Level, obj, reqId string, data interface{}, fields ...Field)
+	LogReqErr(logLevel Level, obj, reqId, info string, err interface{}, fields ...Field)
+	LogReqThirdPart(logLevel Level, obj, reqId, host, info string, cost time.Duration, fields ...Field)
+	LogReqBegin(logLevel Level, reqId, reqClientIP, reqUri, reqParams string, cost time.Duration, fields ...Field)
+	LogReqEnd(logLevel Level, reqId, retData string, cost time.Duration, fields ...Field)
+	LogReqEndCode(logLevel Level, reqId, reqHost string, retCode interface{}, retSize int, retData string, cost time.Duration, fields ...Field)
+
+	// Log*f
+	Logf(logLevel Level, obj, format string, args ...interface{})
+	LogErrf(logLevel Level, obj string, err interface{}, format string, args ...interface{})
+	LogReqf(logLevel Level, obj, reqId, format string, args ...interface{})
+	LogReqErrf(logLevel Level, obj, reqId string, err interface{}, format string, args ...interface{})
+
+	// LogCtx*
+	LogCtx(ctx context.Context, logLevel Level, obj, info string)
+	LogCtxData(ctx context.Context, logLevel Level, obj string, data interface{})
+	LogCtxErr(ctx context.Context, logLevel Level, obj, info string, err interface{})
+	LogCtxThirdPart(ctx context.Context, logLevel Level, obj, host, info string, cost time.Duration)
+}
+
+// Logger 是一个独立的日志实例，拥有自己的配置、日志文件和缓冲队列
+// 一个进程中可以同时存在多个 Logger，互不影响
+//
+// 注意：Logger 的每个方法都必须直接调用 zlogger 的方法，不能再嵌套其他调用，
+// 否则日志中 file 字段记录的调用位置会出错
+type Logger struct {
+	zl zlogger
+}
+
+// New 根据 conf 创建一个独立的 Logger
+// conf 中未设置的配置项会使用默认值，见 LogConfig.Reset
+func New(conf *LogConfig) (*Logger, error) {
+	logConf, err := newLogConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	zl, err := newZapLogger(logConf)
+	if err != nil {
+		return nil, err
+	}
+	return &Logger{zl: zl}, nil
+}
+
+func newLogConfig(conf *LogConfig) (*LogConfig, error) {
+	logConf := new(LogConfig)
+	logConf.Reset(conf)
+	if !logConf.MaxLogLevel.valid() {
+		return nil, fmt.Errorf("zlog level is error: the level[%d] doesnot exist!", logConf.MaxLogLevel)
+	}
+	if logConf.StackLevel != nil && !logConf.StackLevel.valid() {
+		return nil, fmt.Errorf("zlog level is error: the stack level[%d] doesnot exist!", *logConf.StackLevel)
+	}
+	if !logConf.UnknownLevelFallback.valid() {
+		return nil, fmt.Errorf("zlog level is error: the fallback level[%d] doesnot exist!", logConf.UnknownLevelFallback)
+	}
+	if !validCostUnit(logConf.CostUnit) {
+		return nil, fmt.Errorf("zlog cost unit is error: the unit[%s] doesnot exist!", logConf.CostUnit)
+	}
+	return logConf, nil
+}
+
+// 强制刷新日志到日志文件中
+func (this *Logger) Sync() error {
+	return this.zl.Sync()
+}
+
+// 关闭 log
+func (this *Logger) Close() error {
+	return this.zl.Close()
+}
+
+// Reload 使用新的配置（日志目录、文件名、大小、级别等）替换当前配置，无需重启进程
+// 切换过程中并发写入的日志不会丢失也不会重复：切换前写入的日志写入旧文件，之后写入的日志写入新文件
+// 通过 With 创建的子 Logger 同样会切换到新配置
+func (this *Logger) Reload(conf *LogConfig) error {
+	logConf, err := newLogConfig(conf)
+	if err != nil {
+		return err
+	}
+	return this.zl.Reload(logConf)
+}
+
+// SetLevel 运行时调整输出的日志级别（同 LogConfig.MaxLogLevel），对通过 With 创建的子 Logger 同样生效
+// 再次调用 Reload 时，日志级别会被重置为新配置中的 MaxLogLevel
+func (this *Logger) SetLevel(level Level) error {
+	if !level.valid() {
+		return fmt.Errorf("zlog level is error: the level[%d] doesnot exist!", level)
+	}
+	this.zl.SetLevel(level)
+	return nil
+}
+
+// 返回当前输出的日志级别
+func (this *Logger) GetLevel() Level {
+	return this.zl.GetLevel()
+}
+
+// SetObjLevels 运行时替换按 obj 覆盖的日志级别（同 LogConfig.ObjLogLevel），levels 为空时清除所有覆盖
+// 再次调用 Reload 时，会被重置为新配置中的 ObjLogLevel
+func (this *Logger) SetObjLevels(levels map[string]Level) error {
+	return this.zl.SetObjLevels(levels)
+}
+
+// 返回当前按 obj 覆盖的日志级别
+func (this *Logger) GetObjLevels() map[string]Level {
+	return this.zl.GetObjLevels()
+}
+
+// 返回出现未知日志级别（如 Level(7)）的次数，见 LogConfig.UnknownLevelFallback
+func (this *Logger) UnknownLevelCount() uint64 {
+	return this.zl.UnknownLevelCount()
+}
+
+// 返回用于查看、调整日志级别的 http.Handler，见 levelHandler
+func (this *Logger) LevelHandler() http.Handler {
+	return &levelHandler{logger: this}
+}
+
+// 返回绑定了 fields 的子 Logger，子 Logger 打印的每条日志末尾都会带上这些 fields
+// fields 只在创建时编码一次，适合 module/tenant 等固定的上下文信息
+// 子 Logger 与父 Logger 共用日志文件，Close 只需在父 Logger 上调用
+func (this *Logger) With(fields ...Field) *Logger {
+	return &Logger{zl: this.zl.With(fields...)}
+}
+
+// 记录服务启动耗时
+// startTimeNS 单位：纳秒
+func (this *Logger) LogStart(logLevel Level, info string, startTimeNS int64) {
+	this.zl.LogStart(logLevel, info, costSince(startTimeNS))
+}
+
+// 同 LogStart，fields 会以 k=v 的形式追加在日志末尾
+func (this *Logger) LogStartw(logLevel Level, info string, startTimeNS int64, fields ...Field) {
+	this.zl.LogStart(logLevel, info, costSince(startTimeNS), fields...)
+}
+
+// 同 LogStartw，耗时由 time.Since(start) 计算，使用单调时钟，不受系统时间调整影响
+func (this *Logger) LogStartSince(logLevel Level, info string, start time.Time, fields ...Field) {
+	this.zl.LogStart(logLevel, info, time.Since(start), fields...)
+}
+
+func (this *Logger) Log(logLevel Level, obj, info string) {
+	this.zl.Log(logLevel, obj, info)
+}
+
+// 同 Log，fields 会以 k=v 的形式追加在日志末尾
+func (this *Logger) Logw(logLevel Level, obj, info string, fields ...Field) {
+	this.zl.Log(logLevel, obj, info, fields...)
+}
+
+// 用于打印离线数据， data=xxx
+// 如果 data 是 struct/map 最终会被 json.Marshal 成字符串
+func (this *Logger) LogData(logLevel Level, obj string, data interface{}) {
+	this.zl.LogData(logLevel, obj, data)
+}
+
+// 同 LogData，fields 会以 k=v 的形式追加在日志末尾
+func (this *Logger) LogDataw(logLevel Level, obj string, data interface{}, fields ...Field) {
+	this.zl.LogData(logLevel, obj, data, fields...)
+}
+
+func (this *Logger) LogErr(logLevel Level, obj, info string, err interface{}) {
+	this.zl.LogErr(logLevel, obj, info, err)
+}
+
+// 同 LogErr，fields 会以 k=v 的形式追加在日志末尾
+func (this *Logger) LogErrw(logLevel Level, obj, info string, err interface{}, fields ...Field) {
+	this.zl.LogErr(logLevel, obj, info, err, fields...)
+}
+
+// （不带reqId）请求了其他组件，比如 mysql、redis、cnd 等
+func (this *Logger) LogThirdPart(logLevel Level, obj, host, info string, startTimeNS int64) {
+	this.zl.LogThirdPart(logLevel, obj, host, info, costSince(startTimeNS))
+}
+
+// 同 LogThirdPart，fields 会以 k=v 的形式追加在日志末尾
+func (this *Logger) LogThirdPartw(logLevel Level, obj, host, info string, startTimeNS int64, fields ...Field) {
+	this.zl.LogThirdPart(logLevel, obj, host, info, costSince(startTimeNS), fields...)
+}
+
+// 同 LogThirdPartw，耗时由 time.Since(start) 计算，使用单调时钟，不受系统时间调整影响
+func (this *Logger) LogThirdPartSince(logLevel Level, obj, host, info string, start time.Time, fields ...Field) {
+	this.zl.LogThirdPart(logLevel, obj, host, info, time.Since(start), fields...)
+}
+
+// 打印 FATAL 日志并触发 panic
+func (this *Logger) LogPanic(obj, info string, err interface{}) {
+	this.zl.LogPanic(obj, info, err)
+}
+
+// 同 LogPanic，fields 会以 k=v 的形式追加在日志末尾
+func (this *Logger) LogPanicw(obj, info string, err interface{}, fields ...Field) {
+	this.zl.LogPanic(obj, info, err, fields...)
+}
+
+// 打印 FATAL 日志，同步刷新日志文件、执行通过 RegisterShutdownHook 注册的方法后退出进程
+// 退出码见 LogConfig.FatalExitCode
+func (this *Logger) LogFatal(obj, info string, err interface{}) {
+	this.zl.LogFatal(obj, info, err)
+}
+
+// 同 LogFatal，fields 会以 k=v 的形式追加在日志末尾
+func (this *Logger) LogFatalw(obj, info string, err interface{}, fields ...Field) {
+	this.zl.LogFatal(obj, info, err, fields...)
+}
+
+func (this *Logger) LogReq(logLevel Level, obj, reqId, info string) {
+	this.zl.LogReq(logLevel, obj, reqId, info)
+}
+
+// 同 LogReq，fields 会以 k=v 的形式追加在日志末尾
+func (this *Logger) LogReqw(logLevel Level, obj, reqId, info string, fields ...Field) {
+	this.zl.LogReq(logLevel, obj, reqId, info, fields...)
+}
+
+// 用于打印离线数据， data=xxx
+// 如果 data 是 struct/map 最终会被 json.Marshal 成字符串
+func (this *Logger) LogReqData(logLevel Level, obj, reqId string, data interface{}) {
+	this.zl.LogReqData(logLevel, obj, reqId, data)
+}
+
+// 同 LogReqData，fields 会以 k=v 的形式追加在日志末尾
+func (this *Logger) LogReqDataw(logLevel Level, obj, reqId string, data interface{}, fields ...Field) {
+	this.zl.LogReqData(logLevel, obj, reqId, data, fields...)
+}
+
+func (this *Logger) LogReqErr(logLevel Level, obj, reqId, info string, err interface{}) {
+	this.zl.LogReqErr(logLevel, obj, reqId, info, err)
+}
+
+// 同 LogReqErr，fields 会以 k=v 的形式追加在日志末尾
+func (this *Logger) LogReqErrw(logLevel Level, obj, reqId, info string, err interface{}, fields ...Field) {
+	this.zl.LogReqErr(logLevel, obj, reqId, info, err, fields...)
+}
+
+// （带reqId）业务请求中，请求了其他组件，比如 mysql、redis、cnd 等
+func (this *Logger) LogReqThirdPart(logLevel Level, obj, reqId, host, info string, startTimeNS int64) {
+	this.zl.LogReqThirdPart(logLevel, obj, reqId, host, info, costSince(startTimeNS))
+}
+
+// 同 LogReqThirdPart，fields 会以 k=v 的形式追加在日志末尾
+func (this *Logger) LogReqThirdPartw(logLevel Level, obj, reqId, host, info string, startTimeNS int64, fields ...Field) {
+	this.zl.LogReqThirdPart(logLevel, obj, reqId, host, info, costSince(startTimeNS), fields...)
+}
+
+// 同 LogReqThirdPartw，耗时由 time.Since(start) 计算，使用单调时钟，不受系统时间调整影响
+func (this *Logger) LogReqThirdPartSince(logLevel Level, obj, reqId, host, info string, start time.Time, fields ...Field) {
+	this.zl.LogReqThirdPart(logLevel, obj, reqId, host, info, time.Since(start), fields...)
+}
+
+// 完整接收到请求数据之后，打印此日志
+// startTimeNS 指开始接受请求的时间
+func (this *Logger) LogReqBegin(logLevel Level, reqId, reqClientIP, reqUri, reqParams string, startTimeNS int64) {
+	this.zl.LogReqBegin(logLevel, reqId, reqClientIP, reqUri, reqParams, costSince(startTimeNS))
+}
+
+// 同 LogReqBegin，fields 会以 k=v 的形式追加在日志末尾
+func (this *Logger) LogReqBeginw(logLevel Level, reqId, reqClientIP, reqUri, reqParams string, startTimeNS int64, fields ...Field) {
+	this.zl.LogReqBegin(logLevel, reqId, reqClientIP, reqUri, reqParams, costSince(startTimeNS), fields...)
+}
+
+// 同 LogReqBeginw，耗时由 time.Since(start) 计算，使用单调时钟，不受系统时间调整影响
+func (this *Logger) LogReqBeginSince(logLevel Level, reqId, reqClientIP, reqUri, reqParams string, start time.Time, fields ...Field) {
+	this.zl.LogReqBegin(logLevel, reqId, reqClientIP, reqUri, reqParams, time.Since(start), fields...)
+}
+
+// 请求处理结束，打印此日志
+// startTimeNS 指开始接受请求的时间，同 LogReqBegin 中的 startTimeNS
+// retData 返回的数据
+func (this *Logger) LogReqEnd(logLevel Level, reqId, retData string, startTimeNS int64) {
+	this.zl.LogReqEnd(logLevel, reqId, retData, costSince(startTimeNS))
+}
+
+// 同 LogReqEnd，fields 会以 k=v 的形式追加在日志末尾
+func (this *Logger) LogReqEndw(logLevel Level, reqId, retData string, startTimeNS int64, fields ...Field) {
+	this.zl.LogReqEnd(logLevel, reqId, retData, costSince(startTimeNS), fields...)
+}
+
+// 同 LogReqEndw，耗时由 time.Since(start) 计算，使用单调时钟，不受系统时间调整影响
+func (this *Logger) LogReqEndSince(logLevel Level, reqId, retData string, start time.Time, fields ...Field) {
+	this.zl.LogReqEnd(logLevel, reqId, retData, time.Since(start), fields...)
+}
+
+// 请求处理结束，打印带有返回码的 RE 日志：obj=RE reqId reqHost retCode retSize retData cost
+// 日志级别由 retCode 决定（默认 4xx 为 WARN、5xx 为 ERROR，其他为 INFO），见 LogConfig.RetCodeLevel
+// retCode 可以是整数或字符串；reqHost 为空时不输出 reqHost，retSize 小于 0 时不输出 retSize
+func (this *Logger) LogReqEndCode(reqId, reqHost string, retCode interface{}, retSize int, retData string, startTimeNS int64) {
+	this.zl.LogReqEndCode(this.zl.retCodeLevel(retCode), reqId, reqHost, retCode, retSize, retData, costSince(startTimeNS))
+}
+
+// 同 LogReqEndCode，fields 会以 k=v 的形式追加在日志末尾
+func (this *Logger) LogReqEndCodew(reqId, reqHost string, retCode interface{}, retSize int, retData string, startTimeNS int64, fields ...Field) {
+	this.zl.LogReqEndCode(this.zl.retCodeLevel(retCode), reqId, reqHost, retCode, retSize, retData, costSince(startTimeNS), fields...)
+}
+
+// 同 LogReqEndCodew，耗时由 time.Since(start) 计算，使用单调时钟，不受系统时间调整影响
+func (this *Logger) LogReqEndCodeSince(reqId, reqHost string, retCode interface{}, retSize int, retData string, start time.Time, fields ...Field) {
+	this.zl.LogReqEndCode(this.zl.retCodeLevel(retCode), reqId, reqHost, retCode, retSize, retData, time.Since(start), fields...)
+}
+
+// 同 LogReqEndCodeSince，日志级别由 logLevel 指定，用于返回码不适用 LogConfig.RetCodeLevel 的场景，如 gRPC 的状态码
+func (this *Logger) LogReqEndCodeLevel(logLevel Level, reqId, reqHost string, retCode interface{}, retSize int, retData string, start time.Time, fields ...Field) {
+	this.zl.LogReqEndCode(logLevel, reqId, reqHost, retCode, retSize, retData, time.Since(start), fields...)
+}
+
+// 从 ctx 中取出 reqId 及 fields（见 NewContext）打印日志，ctx 中没有 reqId 时不输出 reqId 字段
+func (this *Logger) LogCtx(ctx context.Context, logLevel Level, obj, info string) {
+	this.zl.LogCtx(ctx, logLevel, obj, info)
+}
+
+// 用于打印离线数据， data=xxx
+// 如果 data 是 struct/map 最终会被 json.Marshal 成字符串
+func (this *Logger) LogCtxData(ctx context.Context, logLevel Level, obj string, data interface{}) {
+	this.zl.LogCtxData(ctx, logLevel, obj, data)
+}
+
+func (this *Logger) LogCtxErr(ctx context.Context, logLevel Level, obj, info string, err interface{}) {
+	this.zl.LogCtxErr(ctx, logLevel, obj, info, err)
+}
+
+// 业务请求中，请求了其他组件，比如 mysql、redis、cnd 等
+func (this *Logger) LogCtxThirdPart(ctx context.Context, logLevel Level, obj, host, info string, startTimeNS int64) {
+	this.zl.LogCtxThirdPart(ctx, logLevel, obj, host, info, costSince(startTimeNS))
+}
+
+// info 按 fmt.Sprintf(format, args...) 格式化，logLevel 未开启时不会执行格式化
+func (this *Logger) Logf(logLevel Level, obj, format string, args ...interface{}) {
+	this.zl.Logf(logLevel, obj, format, args...)
+}
+
+func (this *Logger) LogErrf(logLevel Level, obj string, err interface{}, format string, args ...interface{}) {
+	this.zl.LogErrf(logLevel, obj, err, format, args...)
+}
+
+func (this *Logger) LogReqf(logLevel Level, obj, reqId, format string, args ...interface{}) {
+	this.zl.LogReqf(logLevel, obj, reqId, format, args...)
+}
+
+func (this *Logger) LogReqErrf(logLevel Level, obj, reqId string, err interface{}, format string, args ...interface{}) {
+	this.zl.LogReqErrf(logLevel, obj, reqId, err, format, args...)
 }
@@ -1,8 +1,11 @@
 package zlog
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"runtime"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -12,13 +15,39 @@ import (
 
 type _TYPE_ZAP_LOG_fUNC func(msg string, fields ...zap.Field)
 
+const (
+	// 未知日志级别告警的最小间隔
+	unknownLevelWarnInterval = time.Minute
+)
+
 var (
 	zapEnableErrLogLevel = zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
 		return lvl >= zapcore.ErrorLevel
 	})
 )
 
-func newZapLogger(logConf *LogConfig) zlogger {
+func newZapLogger(logConf *LogConfig) (zlogger, error) {
+	objLevels, err := newObjLevelRules(logConf.ObjLogLevel)
+	if err != nil {
+		return nil, err
+	}
+	retCodeLevels, err := newRetCodeLevels(logConf.RetCodeLevel)
+	if err != nil {
+		return nil, err
+	}
+	shared := new(zapShared)
+	shared.retCodeLevels.Store(retCodeLevels)
+	shared.filter = newLevelFilter(logConf.MaxLogLevel, objLevels)
+	cores, closer := newZapCores(logConf, shared.filter)
+	shared.core = newReloadCore(cores, closer)
+	shared.setConf(logConf)
+	logger := zap.New(shared.core, zap.AddCaller(), zap.AddCallerSkip(2))
+	return wrapZapLogger(logger, shared, shared.core), nil
+}
+
+// 根据配置创建 cores，closer 用于关闭 cores 使用的 writer
+// filter 控制 program.log 输出的日志级别，在 Reload 前后保持同一个，以便运行时调整
+func newZapCores(logConf *LogConfig, filter *levelFilter) ([]zapcore.Core, io.Closer) {
 	// encoder
 	zapEncoderConf := zap.NewProductionEncoderConfig()
 	zapEncoderConf.MessageKey = LK_LOG_LEV
@@ -26,7 +55,9 @@ func newZapLogger(logConf *LogConfig) zlogger {
 	zapEncoderConf.CallerKey = LK_FILE
 	zapEncoderConf.EncodeLevel = zapcore.CapitalLevelEncoder
 	zapEncoderConf.EncodeTime = dayMilliTimeEncoder
-	zapEncoder := newZapKVTabEncoder(zapEncoderConf)
+	zapEncoderConf.EncodeDuration = zapcore.StringDurationEncoder
+	zapEncoderConf.StacktraceKey = LK_STACK
+	zapEncoder := newZapKVTabEncoder(zapEncoderConf, logConf.CostUnit)
 
 	// writer
 	// normal log write use buffer
@@ -39,37 +70,110 @@ func newZapLogger(logConf *LogConfig) zlogger {
 	allLevelWriteSyncer, syncerCloser := newBufferWriteSyncer(zapcore.AddSync(allLogger), 0, 20*time.Second)
 
 	errLogFileName := logConf.GetErrorLogFilePath()
-	errLevelWriteSyncer := zapcore.AddSync(&lumberjack.Logger{
+	errLogger := &lumberjack.Logger{
 		Filename:   errLogFileName,
 		MaxSize:    logConf.MaxLogSizeMB,
 		MaxBackups: logConf.MaxLogFileNum,
 		LocalTime:  true,
-	})
+	}
+	errLevelWriteSyncer := zapcore.AddSync(errLogger)
 
-	// logger
-	dLevel := zap.NewAtomicLevelAt(getZapLevel(logConf.MaxLogLevel))
-	core := zapcore.NewTee(
-		zapcore.NewCore(zapEncoder, allLevelWriteSyncer, dLevel),
+	// core
+	cores := []zapcore.Core{
+		programCore{zapcore.NewCore(zapEncoder, allLevelWriteSyncer, filter)},
 		zapcore.NewCore(zapEncoder, errLevelWriteSyncer, zapEnableErrLogLevel),
-	)
-	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(2))
+	}
 
+	// flush buffered logs first, then close log files
+	closer := closerFunc(func() error {
+		err := syncerCloser.Close()
+		if e := allLogger.Close(); e != nil && err == nil {
+			err = e
+		}
+		if e := errLogger.Close(); e != nil && err == nil {
+			err = e
+		}
+		return err
+	})
+	return cores, closer
+}
+
+func wrapZapLogger(logger *zap.Logger, shared *zapShared, closer io.Closer) *zapLogger {
 	zlogger := new(zapLogger)
-	zlogger.closer = syncerCloser
+	zlogger.shared = shared
+	zlogger.closer = closer
 	zlogger.logger = logger
-	zlogger.logFuncMap = make(map[string]_TYPE_ZAP_LOG_fUNC, 5)
-	zlogger.logFuncMap[LL_DEBUG] = logger.Debug
-	zlogger.logFuncMap[LL_INFO] = logger.Info
-	zlogger.logFuncMap[LL_WARN] = logger.Warn
-	zlogger.logFuncMap[LL_ERROR] = logger.Error
-	zlogger.logFuncMap[LL_FATAL] = logger.Error // zap FATAL will exec os.Exit
+	zlogger.logFuncMap = newLogFuncMap(logger)
+	// used by the closure returned from unknownLevelLogFunc, which adds one more frame
+	zlogger.skipLogFuncMap = newLogFuncMap(logger.WithOptions(zap.AddCallerSkip(1)))
 	return zlogger
 }
 
+func newLogFuncMap(logger *zap.Logger) map[Level]_TYPE_ZAP_LOG_fUNC {
+	logFuncMap := make(map[Level]_TYPE_ZAP_LOG_fUNC, 5)
+	logFuncMap[LL_DEBUG] = logger.Debug
+	logFuncMap[LL_INFO] = logger.Info
+	logFuncMap[LL_WARN] = logger.Warn
+	logFuncMap[LL_ERROR] = logger.Error
+	logFuncMap[LL_FATAL] = logger.Error // zap FATAL will exec os.Exit
+	return logFuncMap
+}
+
+func nopLogFunc(msg string, fields ...zap.Field) {}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
 type zapLogger struct {
 	logger     *zap.Logger
+	shared     *zapShared
 	closer     io.Closer
-	logFuncMap map[string]_TYPE_ZAP_LOG_fUNC
+	logFuncMap map[Level]_TYPE_ZAP_LOG_fUNC
+
+	skipLogFuncMap map[Level]_TYPE_ZAP_LOG_fUNC
+}
+
+// zapShared 是 zapLogger 及其子 logger（With）共用的状态，Reload 时原地更新
+type zapShared struct {
+	core   *reloadCore
+	filter *levelFilter
+
+	fatalExitCode        int32 // atomic
+	unknownLevelFallback int32 // atomic
+	unknownLevelCount    uint64
+	unknownLevelWarnTime int64        // 上次打印未知日志级别告警的时间，UnixNano
+	stackLevel           int32        // atomic, Level，不记录堆栈时为 stackDisabled
+	stackMaxDepth        int32        // atomic
+	stackSkip            int32        // atomic
+	retCodeLevels        atomic.Value // *retCodeLevels
+}
+
+// 更新 cores、日志级别之外的配置
+func (this *zapShared) setConf(logConf *LogConfig) {
+	atomic.StoreInt32(&this.fatalExitCode, int32(logConf.FatalExitCode))
+	atomic.StoreInt32(&this.unknownLevelFallback, int32(logConf.UnknownLevelFallback))
+	stackLevel := stackDisabled
+	if logConf.StackLevel != nil {
+		stackLevel = int32(*logConf.StackLevel)
+	}
+	atomic.StoreInt32(&this.stackLevel, stackLevel)
+	atomic.StoreInt32(&this.stackMaxDepth, int32(logConf.StackDepth))
+	atomic.StoreInt32(&this.stackSkip, int32(logConf.StackSkip))
+}
+
+// logLevel 级别的日志是否需要记录堆栈，见 LogConfig.StackLevel
+func (this *zapShared) stackEnabled(logLevel Level) bool {
+	return int32(logLevel) >= atomic.LoadInt32(&this.stackLevel)
+}
+
+// 返回 retCode 对应的日志级别，见 LogConfig.RetCodeLevel
+func (this *zapShared) retCodeLevel(retCode interface{}) Level {
+	return this.retCodeLevels.Load().(*retCodeLevels).level(retCode)
+}
+
+func (this *zapShared) stackDepth() int {
+	return int(atomic.LoadInt32(&this.stackMaxDepth))
 }
 
 // 强制刷新日志到日志文件中
@@ -82,123 +186,440 @@ func (this *zapLogger) Close() error {
 	return this.closer.Close()
 }
 
-func (this *zapLogger) getLogFunc(logLevel string) _TYPE_ZAP_LOG_fUNC {
+// 返回绑定了 fields 的子 logger，fields 只在此处编码一次
+// 子 logger 与父 logger 共用日志文件，Close 需要在父 logger 上调用
+func (this *zapLogger) With(fields ...Field) zlogger {
+	if len(fields) == 0 {
+		return this
+	}
+	return wrapZapLogger(this.logger.With(fields...), this.shared, nopCloser{})
+}
+
+// 使用新的配置重建 cores 及 writer，旧 writer 中缓存的日志写入旧文件后关闭
+// 通过 With 创建的子 logger 共用 cores，同样会切换到新配置
+func (this *zapLogger) Reload(logConf *LogConfig) error {
+	objLevels, err := newObjLevelRules(logConf.ObjLogLevel)
+	if err != nil {
+		return err
+	}
+	retCodeLevels, err := newRetCodeLevels(logConf.RetCodeLevel)
+	if err != nil {
+		return err
+	}
+	shared := this.shared
+	cores, closer := newZapCores(logConf, shared.filter)
+	err = shared.core.reload(cores, closer)
+	shared.filter.level.SetLevel(getZapLevel(logConf.MaxLogLevel))
+	shared.filter.objLevels.Store(objLevels)
+	shared.retCodeLevels.Store(retCodeLevels)
+	shared.setConf(logConf)
+	return err
+}
+
+// 运行时调整 program.log 输出的日志级别，对通过 With 创建的子 logger 同样生效
+func (this *zapLogger) SetLevel(level Level) {
+	this.shared.filter.level.SetLevel(getZapLevel(level))
+}
+
+func (this *zapLogger) GetLevel() Level {
+	return levelOfZapLevel(this.shared.filter.level.Level())
+}
+
+// 运行时替换按 obj 覆盖的日志级别，见 objLevelRules
+func (this *zapLogger) SetObjLevels(levels map[string]Level) error {
+	objLevels, err := newObjLevelRules(levels)
+	if err != nil {
+		return err
+	}
+	this.shared.filter.objLevels.Store(objLevels)
+	return nil
+}
+
+func (this *zapLogger) GetObjLevels() map[string]Level {
+	return this.shared.filter.rules().copyLevels()
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// obj 配置了日志级别且 logLevel 低于该级别时，返回空函数，不会对日志进行编码
+// logLevel 不低于 ERROR 时依然写入 error-program.log
+func (this *zapLogger) getLogFunc(logLevel Level, obj string) _TYPE_ZAP_LOG_fUNC {
 	logFunc, isOK := this.logFuncMap[logLevel]
 	if !isOK {
-		this.logger.Fatal("zlog", zap.String(LK_INFO, "logLevel is error:"+logLevel))
-		logFunc = this.logger.Info
+		return this.unknownLevelLogFunc(logLevel, obj)
+	}
+	enabled, programLog := this.objEnabled(logLevel, obj)
+	if !enabled {
+		return nopLogFunc
+	}
+	var extra []Field
+	if !programLog {
+		extra = append(extra, skipProgramLogField)
+	}
+	if this.shared.stackEnabled(logLevel) {
+		stack, isOK := this.stackField(logLevel)
+		if !isOK {
+			return nopLogFunc
+		}
+		extra = append(extra, stack)
+	}
+	if len(extra) == 0 {
+		return logFunc
 	}
-	return logFunc
+	logFunc = this.skipLogFuncMap[logLevel]
+	return func(msg string, fields ...zap.Field) {
+		logFunc(msg, append(fields, extra...)...)
+	}
+}
+
+// 返回 logLevel、obj 的日志是否输出，及是否输出到 program.log：
+// obj 级别只作用于 program.log，不低于 ERROR 的日志始终写入 error-program.log
+func (this *zapLogger) objEnabled(logLevel Level, obj string) (enabled, programLog bool) {
+	programLog = this.shared.filter.objEnabled(logLevel, obj)
+	return programLog || logLevel >= ErrorLevel, programLog
+}
+
+// 返回追加在日志末尾的 stack=xxx，堆栈从调用 zlog 的位置开始；日志不会输出时返回 false
+func (this *zapLogger) stackField(logLevel Level) (Field, bool) {
+	zapLevel, _ := getZapLogLevel(logLevel)
+	if !this.logger.Core().Enabled(zapLevel) {
+		return Field{}, false
+	}
+	// skip stackField, getLogFunc, zapLogger.Log* and Logger.Log*/zlog.Log*
+	skip := 4 + int(atomic.LoadInt32(&this.shared.stackSkip))
+	return zap.String(LK_STACK, takeStack(skip, this.shared.stackDepth())), true
+}
+
+// 未知的 logLevel 按 LogConfig.UnknownLevelFallback 输出，不会退出进程
+// 同时计数（见 UnknownLevelCount），并每隔 unknownLevelWarnInterval 打印一条告警，告警中的 file 即为出错的调用位置
+func (this *zapLogger) unknownLevelLogFunc(logLevel Level, obj string) _TYPE_ZAP_LOG_fUNC {
+	shared := this.shared
+	atomic.AddUint64(&shared.unknownLevelCount, 1)
+	fallback := Level(atomic.LoadInt32(&shared.unknownLevelFallback))
+
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&shared.unknownLevelWarnTime)
+	if now-last >= int64(unknownLevelWarnInterval) && atomic.CompareAndSwapInt64(&shared.unknownLevelWarnTime, last, now) {
+		// skip unknownLevelLogFunc and getLogFunc
+		this.logger.WithOptions(zap.AddCallerSkip(2)).Warn(LL_WARN.tag(),
+			zap.String(LK_OBJ, OBJ_ZLOG),
+			zap.String(LK_INFO, fmt.Sprintf("logLevel is error: %s, fallback to %s", logLevel, fallback)),
+			zap.Uint64("count", atomic.LoadUint64(&shared.unknownLevelCount)),
+		)
+	}
+
+	enabled, programLog := this.objEnabled(fallback, obj)
+	if !enabled {
+		return nopLogFunc
+	}
+	logFunc := this.skipLogFuncMap[fallback]
+	tag := fallback.tag()
+	if !programLog {
+		return func(_ string, fields ...zap.Field) {
+			logFunc(tag, append(fields, skipProgramLogField)...)
+		}
+	}
+	return func(_ string, fields ...zap.Field) {
+		logFunc(tag, fields...)
+	}
+}
+
+// 出现未知日志级别的次数
+func (this *zapLogger) UnknownLevelCount() uint64 {
+	return atomic.LoadUint64(&this.shared.unknownLevelCount)
+}
+
+// logLevel 级别的日志是否会被输出，未知的 logLevel 交由 getLogFunc 处理
+func (this *zapLogger) enabled(logLevel Level, obj string) bool {
+	zapLevel, isOK := getZapLogLevel(logLevel)
+	if !isOK {
+		return true
+	}
+	enabled, _ := this.objEnabled(logLevel, obj)
+	return enabled && this.logger.Core().Enabled(zapLevel)
+}
+
+// 任意 obj 的 logLevel 级别日志是否可能被输出，即不考虑具体 obj 的 enabled
+func (this *zapLogger) levelEnabled(logLevel Level) bool {
+	zapLevel, isOK := getZapLogLevel(logLevel)
+	if !isOK {
+		return true
+	}
+	return this.logger.Core().Enabled(zapLevel)
+}
+
+// retCode 对应的日志级别，见 LogConfig.RetCodeLevel
+func (this *zapLogger) retCodeLevel(retCode interface{}) Level {
+	return this.shared.retCodeLevel(retCode)
+}
+
+// 返回转换为 Log* 格式的 core，见 Logger.ZapCore
+func (this *zapLogger) zapCore() zapcore.Core {
+	return &zapInteropCore{core: this.logger.Core(), filter: this.shared.filter}
 }
 
 // 记录服务启动耗时
-// startTimeNS 单位：纳秒
-func (this *zapLogger) LogStart(logLevel, info string, startTimeNS int64) {
-	this.getLogFunc(logLevel)(logLevel,
+func (this *zapLogger) LogStart(logLevel Level, info string, cost time.Duration, fields ...Field) {
+	this.getLogFunc(logLevel, OBJ_START)(logLevel.tag(), appendFields([]Field{
 		zap.String(LK_OBJ, OBJ_START),
 		zap.String(LK_INFO, info),
-		zap.Int64(LK_COST, getCost(startTimeNS)),
-	)
+		costField(LK_COST, cost),
+	}, fields)...)
 }
 
-func (this *zapLogger) Log(logLevel, obj, info string) {
-	this.getLogFunc(logLevel)(logLevel,
+// 打印由调用方拼装好固定字段 base（需包含 obj）的日志，With 绑定的字段位于 base 与 fields 之间
+func (this *zapLogger) logBase(logLevel Level, obj string, base, fields []Field) {
+	this.getLogFunc(logLevel, obj)(logLevel.tag(), appendFields(base, fields)...)
+}
+
+func (this *zapLogger) Log(logLevel Level, obj, info string, fields ...Field) {
+	this.getLogFunc(logLevel, obj)(logLevel.tag(), appendFields([]Field{
 		zap.String(LK_OBJ, obj),
 		zap.String(LK_INFO, info),
-	)
+	}, fields)...)
 }
 
 // 用于打印离线数据， data=xxx
 // 如果 data 是 struct/map 最终会被 json.Marshal 成字符串
-func (this *zapLogger) LogData(logLevel, obj string, data interface{}) {
-	this.getLogFunc(logLevel)(logLevel,
+func (this *zapLogger) LogData(logLevel Level, obj string, data interface{}, fields ...Field) {
+	this.getLogFunc(logLevel, obj)(logLevel.tag(), appendFields([]Field{
 		zap.String(LK_OBJ, obj),
 		zap.Any(LK_DATA, data),
-	)
+	}, fields)...)
 }
 
-func (this *zapLogger) LogErr(logLevel, obj, info string, err interface{}) {
-	this.getLogFunc(logLevel)(logLevel,
+func (this *zapLogger) LogErr(logLevel Level, obj, info string, err interface{}, fields ...Field) {
+	this.getLogFunc(logLevel, obj)(logLevel.tag(), appendFields([]Field{
 		zap.String(LK_OBJ, obj),
 		zap.String(LK_INFO, info),
 		zap.Any(LK_ERR, err),
-	)
+	}, fields)...)
 }
 
-func (this *zapLogger) LogThirdPart(logLevel, obj, host, info string, startTimeNS int64) {
-	this.getLogFunc(logLevel)(logLevel,
+func (this *zapLogger) LogThirdPart(logLevel Level, obj, host, info string, cost time.Duration, fields ...Field) {
+	this.getLogFunc(logLevel, obj)(logLevel.tag(), appendFields([]Field{
 		zap.String(LK_OBJ, obj),
 		zap.String(LK_HOST, host),
 		zap.String(LK_INFO, info),
-		zap.Int64(LK_COST, getCost(startTimeNS)),
-	)
+		costField(LK_COST, cost),
+	}, fields)...)
 }
 
-func (this *zapLogger) LogPanic(obj, info string, err interface{}) {
-	this.getLogFunc(LL_FATAL)(LL_FATAL,
+func (this *zapLogger) LogPanic(obj, info string, err interface{}, fields ...Field) {
+	this.getLogFunc(LL_FATAL, obj)(LL_FATAL.tag(), appendFields([]Field{
 		zap.String(LK_OBJ, obj),
 		zap.String(LK_INFO, info),
 		zap.Any(LK_ERR, err),
-	)
+	}, fields)...)
 	panic(fmt.Sprintf("info=%s\terr=%v", info, err))
 }
 
-func (this *zapLogger) LogReq(logLevel, obj, reqId, info string) {
-	this.getLogFunc(logLevel)(logLevel,
+// 以 FATAL 级别打印日志，并同步刷新日志文件、执行通过 RegisterShutdownHook 注册的方法，最后退出进程
+// 不受日志级别及 obj 级别配置的影响
+func (this *zapLogger) LogFatal(obj, info string, err interface{}, fields ...Field) {
+	fields = appendFields([]Field{
 		zap.String(LK_OBJ, obj),
-		zap.String(LK_REQ_ID, reqId),
 		zap.String(LK_INFO, info),
+		zap.Any(LK_ERR, err),
+	}, fields)
+	if this.shared.stackEnabled(LL_FATAL) {
+		fields = append(fields, zap.String(LK_STACK, takeStack(2, this.shared.stackDepth())))
+	}
+	this.writeFatal(zapcore.NewEntryCaller(runtime.Caller(2)), fields)
+	runShutdownHooks()
+	this.logger.Sync()
+	exit(int(atomic.LoadInt32(&this.shared.fatalExitCode)))
+}
+
+// 记录 Recover 捕获到的 panic，caller 为触发 panic 的位置，必须在 panic 过程中调用
+func (this *zapLogger) logRecovered(obj, reqId string, r interface{}) {
+	caller, stack := panicStack(this.shared.stackDepth())
+	fields := make([]Field, 0, 5)
+	fields = append(fields, zap.String(LK_OBJ, obj))
+	if reqId != "" {
+		fields = append(fields, zap.String(LK_REQ_ID, reqId))
+	}
+	fields = append(fields,
+		zap.String(LK_INFO, "recover from panic"),
+		zap.Any(LK_ERR, r),
+		zap.String(LK_STACK, stack),
 	)
+	this.writeFatal(caller, fields)
+}
+
+// 以 logLevel 级别写入日志，时间及 caller 由 ent 指定，fields 需包含 obj
+// 用于 RedirectStdLog、slog 等无法通过 caller skip 确定调用位置的场景
+func (this *zapLogger) logCaller(logLevel Level, obj string, ent zapcore.Entry, fields ...Field) {
+	zapLevel, isOK := getZapLogLevel(logLevel)
+	if !isOK {
+		return
+	}
+	enabled, programLog := this.objEnabled(logLevel, obj)
+	if !enabled {
+		return
+	}
+	if !programLog {
+		fields = append(fields, skipProgramLogField)
+	}
+	ent.Level = zapLevel
+	ent.Message = logLevel.tag()
+	if ent.Time.IsZero() {
+		ent.Time = time.Now()
+	}
+	if ce := this.logger.Core().Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// 以 FATAL 级别写入日志（program.log 及 error-program.log）并同步刷新，不会退出进程
+// zap FATAL will exec os.Exit before we flush the buffered log, so write the entry by core directly
+func (this *zapLogger) writeFatal(caller zapcore.EntryCaller, fields []Field) {
+	ent := zapcore.Entry{
+		Time:    time.Now(),
+		Level:   zapcore.FatalLevel,
+		Message: LL_FATAL.tag(),
+		Caller:  caller,
+	}
+	if ce := this.logger.Core().Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	this.logger.Sync()
+}
+
+func (this *zapLogger) LogReq(logLevel Level, obj, reqId, info string, fields ...Field) {
+	this.getLogFunc(logLevel, obj)(logLevel.tag(), reqFields(obj, reqId, fields,
+		zap.String(LK_INFO, info),
+	)...)
 }
 
 // 用于打印离线数据， data=xxx
 // 如果 data 是 struct/map 最终会被 json.Marshal 成字符串
-func (this *zapLogger) LogReqData(logLevel, obj, reqId string, data interface{}) {
-	this.getLogFunc(logLevel)(logLevel,
-		zap.String(LK_OBJ, obj),
-		zap.String(LK_REQ_ID, reqId),
+func (this *zapLogger) LogReqData(logLevel Level, obj, reqId string, data interface{}, fields ...Field) {
+	this.getLogFunc(logLevel, obj)(logLevel.tag(), reqFields(obj, reqId, fields,
 		zap.Any(LK_DATA, data),
-	)
+	)...)
 }
 
-func (this *zapLogger) LogReqErr(logLevel, obj, reqId, info string, err interface{}) {
-	this.getLogFunc(logLevel)(logLevel,
-		zap.String(LK_OBJ, obj),
-		zap.String(LK_REQ_ID, reqId),
+func (this *zapLogger) LogReqErr(logLevel Level, obj, reqId, info string, err interface{}, fields ...Field) {
+	this.getLogFunc(logLevel, obj)(logLevel.tag(), reqFields(obj, reqId, fields,
 		zap.String(LK_INFO, info),
 		zap.Any(LK_ERR, err),
-	)
+	)...)
 }
 
-func (this *zapLogger) LogReqThirdPart(logLevel, obj, reqId, host, info string, startTimeNS int64) {
-	this.getLogFunc(logLevel)(logLevel,
-		zap.String(LK_OBJ, obj),
-		zap.String(LK_REQ_ID, reqId),
+func (this *zapLogger) LogReqThirdPart(logLevel Level, obj, reqId, host, info string, cost time.Duration, fields ...Field) {
+	this.getLogFunc(logLevel, obj)(logLevel.tag(), reqFields(obj, reqId, fields,
 		zap.String(LK_HOST, host),
 		zap.String(LK_INFO, info),
-		zap.Int64(LK_COST, getCost(startTimeNS)),
-	)
+		costField(LK_COST, cost),
+	)...)
 }
 
-func (this *zapLogger) LogReqBegin(logLevel, reqId, reqClientIP, reqUri, reqParams string, startTimeNS int64) {
-	this.getLogFunc(logLevel)(logLevel,
-		zap.String(LK_OBJ, OBJ_RB),
-		zap.String(LK_REQ_ID, reqId),
+func (this *zapLogger) LogReqBegin(logLevel Level, reqId, reqClientIP, reqUri, reqParams string, cost time.Duration, fields ...Field) {
+	this.getLogFunc(logLevel, OBJ_RB)(logLevel.tag(), reqFields(OBJ_RB, reqId, fields,
 		zap.String(LK_REQ_CLIENTIP, reqClientIP),
 		zap.String(LK_REQ_URI, reqUri),
 		zap.String(LK_REQ_PARAMS, reqParams),
-		zap.Int64(LK_COST, getCost(startTimeNS)),
-	)
+		costField(LK_COST, cost),
+	)...)
 }
 
 // 请求处理结束，打印此日志
-// startTimeNS 指开始接受请求的时间，同 RequestBegin 中的 startTimeNS
 // retData 返回的数据
-func (this *zapLogger) LogReqEnd(logLevel, reqId, retData string, startTimeNS int64) {
-	this.getLogFunc(logLevel)(logLevel,
-		zap.String(LK_OBJ, OBJ_RE),
-		zap.String(LK_REQ_ID, reqId),
+func (this *zapLogger) LogReqEnd(logLevel Level, reqId, retData string, cost time.Duration, fields ...Field) {
+	this.getLogFunc(logLevel, OBJ_RE)(logLevel.tag(), reqFields(OBJ_RE, reqId, fields,
+		zap.String(LK_RET_DATA, retData),
+		costField(LK_COST, cost),
+	)...)
+}
+
+// 请求处理结束，打印带有返回码的 RE 日志，日志级别由 retCode 决定，见 LogConfig.RetCodeLevel
+// reqHost 为空时不输出 reqHost，retSize 小于 0 时不输出 retSize
+func (this *zapLogger) LogReqEndCode(logLevel Level, reqId, reqHost string, retCode interface{}, retSize int, retData string, cost time.Duration, fields ...Field) {
+	logFields := make([]Field, 0, 5)
+	if reqHost != "" {
+		logFields = append(logFields, zap.String(LK_REQ_HOST, reqHost))
+	}
+	logFields = append(logFields, zap.Any(LK_RET_CODE, retCode))
+	if retSize >= 0 {
+		logFields = append(logFields, zap.Int(LK_RET_SIZE, retSize))
+	}
+	logFields = append(logFields,
 		zap.String(LK_RET_DATA, retData),
-		zap.Int64(LK_COST, getCost(startTimeNS)),
+		costField(LK_COST, cost),
+	)
+	this.getLogFunc(logLevel, OBJ_RE)(logLevel.tag(), reqFields(OBJ_RE, reqId, fields, logFields...)...)
+}
+
+func (this *zapLogger) LogCtx(ctx context.Context, logLevel Level, obj, info string) {
+	this.getLogFunc(logLevel, obj)(logLevel.tag(), fromContext(ctx).logFields(obj,
+		zap.String(LK_INFO, info),
+	)...)
+}
+
+func (this *zapLogger) LogCtxData(ctx context.Context, logLevel Level, obj string, data interface{}) {
+	this.getLogFunc(logLevel, obj)(logLevel.tag(), fromContext(ctx).logFields(obj,
+		zap.Any(LK_DATA, data),
+	)...)
+}
+
+func (this *zapLogger) LogCtxErr(ctx context.Context, logLevel Level, obj, info string, err interface{}) {
+	this.getLogFunc(logLevel, obj)(logLevel.tag(), fromContext(ctx).logFields(obj,
+		zap.String(LK_INFO, info),
+		zap.Any(LK_ERR, err),
+	)...)
+}
+
+func (this *zapLogger) LogCtxThirdPart(ctx context.Context, logLevel Level, obj, host, info string, cost time.Duration) {
+	this.getLogFunc(logLevel, obj)(logLevel.tag(), fromContext(ctx).logFields(obj,
+		zap.String(LK_HOST, host),
+		zap.String(LK_INFO, info),
+		costField(LK_COST, cost),
+	)...)
+}
+
+func (this *zapLogger) Logf(logLevel Level, obj, format string, args ...interface{}) {
+	if !this.enabled(logLevel, obj) {
+		return
+	}
+	this.getLogFunc(logLevel, obj)(logLevel.tag(),
+		zap.String(LK_OBJ, obj),
+		zap.String(LK_INFO, fmt.Sprintf(format, args...)),
+	)
+}
+
+func (this *zapLogger) LogErrf(logLevel Level, obj string, err interface{}, format string, args ...interface{}) {
+	if !this.enabled(logLevel, obj) {
+		return
+	}
+	this.getLogFunc(logLevel, obj)(logLevel.tag(),
+		zap.String(LK_OBJ, obj),
+		zap.String(LK_INFO, fmt.Sprintf(format, args...)),
+		zap.Any(LK_ERR, err),
+	)
+}
+
+func (this *zapLogger) LogReqf(logLevel Level, obj, reqId, format string, args ...interface{}) {
+	if !this.enabled(logLevel, obj) {
+		return
+	}
+	this.getLogFunc(logLevel, obj)(logLevel.tag(),
+		zap.String(LK_OBJ, obj),
+		zap.String(LK_REQ_ID, reqId),
+		zap.String(LK_INFO, fmt.Sprintf(format, args...)),
+	)
+}
+
+func (this *zapLogger) LogReqErrf(logLevel Level, obj, reqId string, err interface{}, format string, args ...interface{}) {
+	if !this.enabled(logLevel, obj) {
+		return
+	}
+	this.getLogFunc(logLevel, obj)(logLevel.tag(),
+		zap.String(LK_OBJ, obj),
+		zap.String(LK_REQ_ID, reqId),
+		zap.String(LK_INFO, fmt.Sprintf(format, args...)),
+		zap.Any(LK_ERR, err),
 	)
 }
@@ -0,0 +1,103 @@
+package zlog
+
+import (
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// RecoverOption 用于设置 Recover 捕获到 panic 之后的行为
+type RecoverOption func(*recoverOptions)
+
+type recoverOptions struct {
+	rePanic  bool
+	callback func(r interface{})
+}
+
+// 记录日志后，重新触发 panic
+func RePanic() RecoverOption {
+	return func(opts *recoverOptions) {
+		opts.rePanic = true
+	}
+}
+
+// 记录日志后，调用 callback，r 为 panic 的值；与 RePanic 同时使用时，先调用 callback
+func OnPanic(callback func(r interface{})) RecoverOption {
+	return func(opts *recoverOptions) {
+		opts.callback = callback
+	}
+}
+
+// Recover 捕获 panic，以 FATAL 级别记录 panic 的值（err）及堆栈（stack），并同步刷新日志文件
+// 日志中的 file 为触发 panic 的位置，必须以 defer 的方式直接调用：
+//
+//	defer zlog.Recover(OBJ_XXX)
+func Recover(obj string, opts ...RecoverOption) {
+	if r := recover(); r != nil {
+		handlePanic(logger(), obj, "", r, opts)
+	}
+}
+
+// 同 Recover，日志中带有 reqId
+func RecoverReq(obj, reqId string, opts ...RecoverOption) {
+	if r := recover(); r != nil {
+		handlePanic(logger(), obj, reqId, r, opts)
+	}
+}
+
+// 同 zlog.Recover，必须以 defer 的方式直接调用
+func (this *Logger) Recover(obj string, opts ...RecoverOption) {
+	if r := recover(); r != nil {
+		handlePanic(this.zl, obj, "", r, opts)
+	}
+}
+
+// 同 zlog.RecoverReq，必须以 defer 的方式直接调用
+func (this *Logger) RecoverReq(obj, reqId string, opts ...RecoverOption) {
+	if r := recover(); r != nil {
+		handlePanic(this.zl, obj, reqId, r, opts)
+	}
+}
+
+func handlePanic(zl zlogger, obj, reqId string, r interface{}, opts []RecoverOption) {
+	options := new(recoverOptions)
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	zl.logRecovered(obj, reqId, r)
+
+	if options.callback != nil {
+		options.callback(r)
+	}
+	if options.rePanic {
+		panic(r)
+	}
+}
+
+// 返回触发 panic 的位置，以及从该位置开始的堆栈，最多 depth 层
+func panicStack(depth int) (zapcore.EntryCaller, string) {
+	// frames before runtime.gopanic belong to zlog and the deferred call,
+	// frames after it may belong to runtime, such as runtime.panicmem and runtime.sigpanic
+	frames := callerFrames(1, depth+64)
+	start := 0
+	for i, frame := range frames {
+		if frame.Function == "runtime.gopanic" {
+			start = i + 1
+			break
+		}
+	}
+	for start < len(frames)-1 && strings.HasPrefix(frames[start].Function, "runtime.") {
+		start++
+	}
+	frames = frames[start:]
+	if len(frames) > depth {
+		frames = frames[:depth]
+	}
+
+	var caller zapcore.EntryCaller
+	if len(frames) > 0 {
+		caller = zapcore.NewEntryCaller(frames[0].PC, frames[0].File, frames[0].Line, true)
+	}
+	return caller, formatFrames(frames)
+}
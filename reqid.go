@@ -0,0 +1,163 @@
+package zlog
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// reqId 的最大长度，超过时 NormalizeReqID 视为非法
+const MaxReqIDLen = 64
+
+// ReqIDGenerator 用于生成 reqId，实现需要支持并发调用
+type ReqIDGenerator interface {
+	NewReqID() string
+}
+
+// ReqIDGeneratorFunc 将普通函数适配为 ReqIDGenerator
+type ReqIDGeneratorFunc func() string
+
+func (f ReqIDGeneratorFunc) NewReqID() string {
+	return f()
+}
+
+var defaultReqIDGenerator atomic.Value // reqIDGeneratorHolder
+
+// atomic.Value 要求每次存入的类型一致
+type reqIDGeneratorHolder struct {
+	g ReqIDGenerator
+}
+
+func init() {
+	defaultReqIDGenerator.Store(reqIDGeneratorHolder{NewTimeReqIDGenerator()})
+}
+
+// 使用默认的生成器生成 reqId，默认为 NewTimeReqIDGenerator，可通过 SetReqIDGenerator 替换
+func NewReqID() string {
+	return defaultReqIDGenerator.Load().(reqIDGeneratorHolder).g.NewReqID()
+}
+
+// 替换默认的 reqId 生成器，g 为 nil 时恢复为 NewTimeReqIDGenerator
+func SetReqIDGenerator(g ReqIDGenerator) {
+	if g == nil {
+		g = NewTimeReqIDGenerator()
+	}
+	defaultReqIDGenerator.Store(reqIDGeneratorHolder{g})
+}
+
+// NormalizeReqID 校验从外部（如请求 header）读取的 reqId：去掉首尾空白后，
+// 长度不超过 MaxReqIDLen，且只包含字母、数字及 - _ . : 时返回该 reqId，否则返回空字符串
+//
+//	reqId := zlog.NormalizeReqID(r.Header.Get("X-Request-Id"))
+//	if reqId == "" {
+//		reqId = zlog.NewReqID()
+//	}
+func NormalizeReqID(reqId string) string {
+	reqId = strings.TrimSpace(reqId)
+	if len(reqId) > MaxReqIDLen {
+		return ""
+	}
+	for i := 0; i < len(reqId); i++ {
+		c := reqId[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return ""
+		}
+	}
+	return reqId
+}
+
+type timeReqIDGenerator struct {
+	mtx    sync.Mutex
+	lastMs uint64 // 上一个 reqId 使用的毫秒时间戳
+	seq    uint32 // 上一个 reqId 使用的序号
+	node   [3]byte
+}
+
+// 序号占 6 位十六进制
+const maxTimeReqIDSeq = 1<<24 - 1
+
+// NewTimeReqIDGenerator 返回按时间有序的生成器，reqId 为 24 位十六进制：
+// 12 位毫秒时间戳 + 6 位自增序号 + 6 位随机数（每个生成器固定，用于区分进程）
+// 毫秒变化时序号从 0 开始，同一毫秒内序号用尽或时钟回拨时沿用上一个时间戳继续递增，
+// 因此同一生成器生成的 reqId 严格递增，可直接按字符串排序
+func NewTimeReqIDGenerator() ReqIDGenerator {
+	g := new(timeReqIDGenerator)
+	rand.Read(g.node[:])
+	return g
+}
+
+func (this *timeReqIDGenerator) NewReqID() string {
+	return this.newReqID(uint64(time.Now().UnixNano() / int64(time.Millisecond)))
+}
+
+// 以 ms 为当前时间生成 reqId
+func (this *timeReqIDGenerator) newReqID(ms uint64) string {
+	this.mtx.Lock()
+	if ms > this.lastMs {
+		this.lastMs, this.seq = ms, 0
+	} else if this.seq < maxTimeReqIDSeq {
+		this.seq++
+	} else {
+		this.lastMs, this.seq = this.lastMs+1, 0
+	}
+	ms, seq := this.lastMs, this.seq
+	this.mtx.Unlock()
+
+	var b [12]byte
+	binary.BigEndian.PutUint64(b[:8], ms<<16)
+	b[6], b[7], b[8] = byte(seq>>16), byte(seq>>8), byte(seq)
+	copy(b[9:], this.node[:])
+	return hex.EncodeToString(b[:])
+}
+
+type randomReqIDGenerator struct{}
+
+// NewRandomReqIDGenerator 返回随机生成器，reqId 为 128 位随机数的十六进制（32 位）
+func NewRandomReqIDGenerator() ReqIDGenerator {
+	return randomReqIDGenerator{}
+}
+
+func (randomReqIDGenerator) NewReqID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+type counterReqIDGenerator struct {
+	prefix  string
+	counter uint64
+}
+
+// NewCounterReqIDGenerator 返回以主机名为前缀的计数器生成器，reqId 形如 host-kx3b2a-1：
+// 主机名（非法字符替换为 _）+ 生成器创建时间（秒，36 进制，用于区分重启）+ 自增序号
+func NewCounterReqIDGenerator() ReqIDGenerator {
+	host, _ := os.Hostname()
+	host = strings.Map(func(r rune) rune {
+		if NormalizeReqID(string(r)) == "" || r == ':' {
+			return '_'
+		}
+		return r
+	}, host)
+	if host == "" {
+		host = "localhost"
+	}
+	if len(host) > 32 {
+		host = host[:32]
+	}
+	return &counterReqIDGenerator{
+		prefix: host + "-" + strconv.FormatInt(time.Now().Unix(), 36) + "-",
+	}
+}
+
+func (this *counterReqIDGenerator) NewReqID() string {
+	return this.prefix + strconv.FormatUint(atomic.AddUint64(&this.counter, 1), 10)
+}
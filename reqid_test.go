@@ -0,0 +1,102 @@
+package zlog
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReqIDGenerator(t *testing.T) {
+	cases := []struct {
+		g       ReqIDGenerator
+		pattern string
+	}{
+		{NewTimeReqIDGenerator(), `^[0-9a-f]{24}$`},
+		{NewRandomReqIDGenerator(), `^[0-9a-f]{32}$`},
+		{NewCounterReqIDGenerator(), `^[0-9A-Za-z._-]+-[0-9a-z]+-\d+$`},
+	}
+	for _, c := range cases {
+		var mtx sync.Mutex
+		var wg sync.WaitGroup
+		seen := make(map[string]bool)
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					id := c.g.NewReqID()
+					mtx.Lock()
+					seen[id] = true
+					mtx.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if len(seen) != 4000 {
+			t.Errorf("%T generated duplicate reqIds: %d", c.g, len(seen))
+		}
+		for id := range seen {
+			if !regexp.MustCompile(c.pattern).MatchString(id) || NormalizeReqID(id) != id {
+				t.Errorf("%T generated invalid reqId: %s", c.g, id)
+				break
+			}
+		}
+	}
+
+	g := NewTimeReqIDGenerator()
+	ids := make([]string, 100)
+	for i := range ids {
+		ids[i] = g.NewReqID()
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Errorf("time reqIds should be sorted: %v", ids)
+	}
+
+	// 序号用尽及时钟回拨时依然递增
+	tg := &timeReqIDGenerator{}
+	ids = ids[:0]
+	for _, ms := range []uint64{100, 100, 101, 99} {
+		ids = append(ids, tg.newReqID(ms))
+	}
+	tg.seq = maxTimeReqIDSeq - 1
+	for _, ms := range []uint64{101, 101, 101, 102, 102} {
+		ids = append(ids, tg.newReqID(ms))
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Errorf("time reqIds should be increasing: %v", ids)
+			break
+		}
+	}
+	if ids[2][:18] != "000000000065000000" {
+		t.Errorf("seq should be reset when the millisecond changes: %s", ids[2])
+	}
+
+	defer SetReqIDGenerator(nil)
+	SetReqIDGenerator(ReqIDGeneratorFunc(func() string { return "fixed" }))
+	if id := NewReqID(); id != "fixed" {
+		t.Errorf("NewReqID should use the generator set by SetReqIDGenerator, got %s", id)
+	}
+}
+
+func TestNormalizeReqID(t *testing.T) {
+	cases := map[string]string{
+		"abc-123":               "abc-123",
+		"  0af7651916cd43dd  ":  "0af7651916cd43dd",
+		"host.a:1_2":            "host.a:1_2",
+		"":                      "",
+		"a b":                   "",
+		"a\tinfo=forged":        "",
+		"id\nts=forged":         "",
+		"请求":                    "",
+		strings.Repeat("a", 64): strings.Repeat("a", 64),
+		strings.Repeat("a", 65): "",
+	}
+	for id, expected := range cases {
+		if normalized := NormalizeReqID(id); normalized != expected {
+			t.Errorf("NormalizeReqID(%q) should be %q, got %q", id, expected, normalized)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package zlog
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Request 绑定了一次请求的 reqId 及开始时间，用于打印请求处理过程中的日志，无需手动传递 reqId 和 startTimeNS：
+//
+//	r := zlog.BeginRequest(reqId, clientIP, uri, params)
+//	r.Log(zlog.LL_INFO, "USER", "get user")
+//	r.LogThirdPart(zlog.LL_INFO, "MYSQL", host, "select user", begin)
+//	r.End(0, "ok")
+//
+// Request 可以在处理同一请求的多个 goroutine 中并发使用
+type Request struct {
+	zl          zlogger
+	reqId       string
+	start       time.Time
+	trace       TraceContext
+	traceFields []Field
+	ended       int32
+}
+
+// 开始处理请求，打印 RB 日志，返回的 Request 用于打印后续日志，处理结束时需调用 End
+func BeginRequest(reqId, reqClientIP, reqUri, reqParams string, fields ...Field) *Request {
+	r := newRequest(logger(), reqId, TraceContext{})
+	r.zl.LogReqBegin(LL_INFO, reqId, reqClientIP, reqUri, reqParams, 0, fields...)
+	return r
+}
+
+// 同 zlog.BeginRequest
+func (this *Logger) BeginRequest(reqId, reqClientIP, reqUri, reqParams string, fields ...Field) *Request {
+	r := newRequest(this.zl, reqId, TraceContext{})
+	r.zl.LogReqBegin(LL_INFO, reqId, reqClientIP, reqUri, reqParams, 0, fields...)
+	return r
+}
+
+// 同 BeginRequest，tc 为当前服务处理请求的 span（见 TraceContext.NewChild），
+// RB/RE 及通过 Request 打印的日志都会带有 traceId、spanId、parentSpanId 字段，位于 reqId 之后，同 LogCtx*
+func BeginRequestTrace(reqId, reqClientIP, reqUri, reqParams string, tc TraceContext, fields ...Field) *Request {
+	r := newRequest(logger(), reqId, tc)
+	r.zl.LogReqBegin(LL_INFO, reqId, reqClientIP, reqUri, reqParams, 0, r.with(fields)...)
+	return r
+}
+
+// 同 zlog.BeginRequestTrace
+func (this *Logger) BeginRequestTrace(reqId, reqClientIP, reqUri, reqParams string, tc TraceContext, fields ...Field) *Request {
+	r := newRequest(this.zl, reqId, tc)
+	r.zl.LogReqBegin(LL_INFO, reqId, reqClientIP, reqUri, reqParams, 0, r.with(fields)...)
+	return r
+}
+
+func newRequest(zl zlogger, reqId string, tc TraceContext) *Request {
+	return &Request{
+		zl:          zl,
+		reqId:       reqId,
+		start:       time.Now(),
+		trace:       tc,
+		traceFields: requestTraceFields(tc),
+	}
+}
+
+// 返回 tc 的 trace 字段及 traceFieldsMark，tc 无效时返回 nil
+func requestTraceFields(tc TraceContext) []Field {
+	fields := tc.Fields()
+	if len(fields) == 0 {
+		return nil
+	}
+	return append(fields, traceFieldsMark)
+}
+
+// 在 fields 之前加上 trace 字段
+func (this *Request) with(fields []Field) []Field {
+	if len(this.traceFields) == 0 {
+		return fields
+	}
+	ret := make([]Field, 0, len(this.traceFields)+len(fields))
+	ret = append(ret, this.traceFields...)
+	return append(ret, fields...)
+}
+
+// 返回请求的 reqId
+func (this *Request) ReqId() string {
+	return this.reqId
+}
+
+// 返回请求的开始时间
+func (this *Request) Start() time.Time {
+	return this.start
+}
+
+// 返回请求绑定的 TraceContext，未绑定时为零值
+func (this *Request) Trace() TraceContext {
+	return this.trace
+}
+
+// 返回携带 reqId 及 TraceContext 的 context，用于在深层调用中通过 LogCtx* 打印日志
+func (this *Request) Context(ctx context.Context) context.Context {
+	ctx = NewContext(ctx, this.reqId)
+	if this.trace.Valid() {
+		ctx = ContextWithTrace(ctx, this.trace)
+	}
+	return ctx
+}
+
+// 同 LogReqw
+func (this *Request) Log(logLevel Level, obj, info string, fields ...Field) {
+	this.zl.LogReq(logLevel, obj, this.reqId, info, this.with(fields)...)
+}
+
+// 同 LogReqDataw
+func (this *Request) LogData(logLevel Level, obj string, data interface{}, fields ...Field) {
+	this.zl.LogReqData(logLevel, obj, this.reqId, data, this.with(fields)...)
+}
+
+// 同 LogReqErrw
+func (this *Request) LogErr(logLevel Level, obj, info string, err interface{}, fields ...Field) {
+	this.zl.LogReqErr(logLevel, obj, this.reqId, info, err, this.with(fields)...)
+}
+
+// 同 LogReqThirdPartSince，start 为调用第三方服务的开始时间
+func (this *Request) LogThirdPart(logLevel Level, obj, host, info string, start time.Time, fields ...Field) {
+	this.zl.LogReqThirdPart(logLevel, obj, this.reqId, host, info, time.Since(start), this.with(fields)...)
+}
+
+// 同 LogReqf
+func (this *Request) Logf(logLevel Level, obj, format string, args ...interface{}) {
+	if len(this.traceFields) == 0 {
+		this.zl.LogReqf(logLevel, obj, this.reqId, format, args...)
+		return
+	}
+	if this.zl.enabled(logLevel, obj) {
+		this.zl.LogReq(logLevel, obj, this.reqId, fmt.Sprintf(format, args...), this.traceFields...)
+	}
+}
+
+// 同 LogReqErrf
+func (this *Request) LogErrf(logLevel Level, obj string, err interface{}, format string, args ...interface{}) {
+	if len(this.traceFields) == 0 {
+		this.zl.LogReqErrf(logLevel, obj, this.reqId, err, format, args...)
+		return
+	}
+	if this.zl.enabled(logLevel, obj) {
+		this.zl.LogReqErr(logLevel, obj, this.reqId, fmt.Sprintf(format, args...), err, this.traceFields...)
+	}
+}
+
+// 请求处理结束，打印 RE 日志，cost 为 BeginRequest 至今的耗时，日志级别由 retCode 决定（同 LogReqEndCode）
+// 只有第一次调用 End 或 EndCode 会打印日志，之后的调用直接忽略
+func (this *Request) End(retCode interface{}, retData string, fields ...Field) {
+	if !this.markEnded() {
+		return
+	}
+	this.zl.LogReqEndCode(this.zl.retCodeLevel(retCode), this.reqId, "", retCode, -1, retData, time.Since(this.start), this.with(fields)...)
+}
+
+// 同 End，额外记录 reqHost 及返回数据的大小 retSize，见 LogReqEndCode
+func (this *Request) EndCode(reqHost string, retCode interface{}, retSize int, retData string, fields ...Field) {
+	if !this.markEnded() {
+		return
+	}
+	this.zl.LogReqEndCode(this.zl.retCodeLevel(retCode), this.reqId, reqHost, retCode, retSize, retData, time.Since(this.start), this.with(fields)...)
+}
+
+func (this *Request) markEnded() bool {
+	return atomic.CompareAndSwapInt32(&this.ended, 0, 1)
+}
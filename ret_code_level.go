@@ -0,0 +1,85 @@
+package zlog
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 默认的返回码级别：4xx 打 WARN，5xx 打 ERROR，其他打 INFO
+func defaultRetCodeLevel() map[string]Level {
+	return map[string]Level{
+		"4xx": WarnLevel,
+		"5xx": ErrorLevel,
+	}
+}
+
+// retCodeLevels 根据返回码选择 RE 日志的级别（见 LogConfig.RetCodeLevel），支持两种写法：
+// - 完整的返回码，如 "404"、"USER_NOT_FOUND"
+// - 三位数返回码的分类，如 "4xx"，可匹配 400 ~ 499
+// 同时匹配时，完整的返回码优先；都不匹配时为 INFO
+type retCodeLevels struct {
+	exact   map[string]Level
+	classes map[int]Level // key 为返回码的百位数字
+}
+
+// 解析返回码级别配置，levels 为空时返回 nil
+func newRetCodeLevels(levels map[string]Level) (*retCodeLevels, error) {
+	if len(levels) == 0 {
+		return nil, nil
+	}
+
+	rules := &retCodeLevels{
+		exact:   make(map[string]Level, len(levels)),
+		classes: make(map[int]Level),
+	}
+	for code, level := range levels {
+		if !level.valid() {
+			return nil, fmt.Errorf("zlog level is error: the level[%d] of retCode[%s] doesnot exist!", level, code)
+		}
+		if len(code) == 3 && strings.EqualFold(code[1:], "xx") && code[0] >= '1' && code[0] <= '9' {
+			rules.classes[int(code[0]-'0')] = level
+			continue
+		}
+		rules.exact[code] = level
+	}
+	return rules, nil
+}
+
+// 返回 retCode 对应的日志级别，retCode 可以是整数或字符串
+func (this *retCodeLevels) level(retCode interface{}) Level {
+	if this == nil {
+		return InfoLevel
+	}
+
+	code, isNum := retCodeInt(retCode)
+	if level, isOK := this.exact[fmt.Sprint(retCode)]; isOK {
+		return level
+	}
+	if isNum && code >= 100 && code <= 999 {
+		if level, isOK := this.classes[int(code/100)]; isOK {
+			return level
+		}
+	}
+	return InfoLevel
+}
+
+// 支持所有整数类型（包括以整数为底层类型的自定义类型）及数字字符串
+func retCodeInt(retCode interface{}) (int64, bool) {
+	if v, isOK := retCode.(string); isOK {
+		code, err := strconv.ParseInt(v, 10, 64)
+		return code, err == nil
+	}
+	v := reflect.ValueOf(retCode)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if code := v.Uint(); code <= math.MaxInt64 {
+			return int64(code), true
+		}
+	}
+	return 0, false
+}
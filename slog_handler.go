@@ -0,0 +1,172 @@
+package zlog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	DefaultSlogObjKey = "obj"
+	DefaultSlogObj    = "SLOG"
+
+	// 未作为 obj 的属性（如设置了其他 ObjKey）名为 obj 时，改为以该名字输出，避免一行中出现两个 obj
+	slogObjKey = "slogObj"
+)
+
+type SlogOptions struct {
+	// 作为 obj 输出的属性名，默认为 obj；只识别顶层（不在 group 中）的属性
+	ObjKey string
+
+	// 没有 obj 属性时使用的 obj，默认为 SLOG
+	DefaultObj string
+}
+
+// SlogHandler 返回写入默认 Logger 的 slog.Handler，见 Logger.SlogHandler
+func SlogHandler(opts *SlogOptions) slog.Handler {
+	return Default().SlogHandler(opts)
+}
+
+// SlogHandler 返回写入当前 Logger 的 slog.Handler，日志格式与 Log* 一致：
+//
+//	logger := slog.New(zl.SlogHandler(nil))
+//	logger.Info("get user", "obj", "USER", "uid", 1)
+//	// => ... logLev=[INFO]		obj=USER	info=get user	uid=1
+//
+// - 日志级别：低于 slog.LevelInfo 为 DEBUG，低于 slog.LevelWarn 为 INFO，低于 slog.LevelError 为 WARN，其他为 ERROR
+// - ObjKey 属性作为 obj 输出，Message 作为 info 输出，其他属性以 k=v 的形式追加在末尾，group 中的属性以 group.key 的形式输出；ObjKey 不是 obj 时，名为 obj 的属性以 slogObj=xxx 的形式输出
+// - ctx 中携带的 reqId、trace 字段及 fields（见 NewContext）同样会输出
+// opts 为 nil 时使用默认配置
+func (this *Logger) SlogHandler(opts *SlogOptions) slog.Handler {
+	h := &slogHandler{zl: this.zl, objKey: DefaultSlogObjKey, obj: DefaultSlogObj}
+	if opts != nil {
+		if opts.ObjKey != "" {
+			h.objKey = opts.ObjKey
+		}
+		if opts.DefaultObj != "" {
+			h.obj = opts.DefaultObj
+		}
+	}
+	return h
+}
+
+type slogHandler struct {
+	zl     zlogger
+	objKey string
+	obj    string
+	prefix string  // WithGroup 累积的 group 前缀，如 "a.b."
+	fields []Field // WithAttrs 绑定的字段
+}
+
+func slogLevel(level slog.Level) Level {
+	switch {
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	}
+	return ErrorLevel
+}
+
+// obj 可能由 record 中的属性指定，这里只判断级别，obj 级别由 Handle 中的 logCaller 判断
+func (this *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return this.zl.levelEnabled(slogLevel(level))
+}
+
+func (this *slogHandler) Handle(ctx context.Context, record slog.Record) error {
+	obj := this.obj
+	// Logger.With 绑定的字段位于 info 之后，其次是 WithAttrs 绑定的字段及 record 中的属性
+	fields := make([]Field, 0, 2+len(this.fields)+record.NumAttrs())
+	fields = append(fields, zap.String(LK_INFO, record.Message), withFieldsMark)
+	fields = append(fields, this.fields...)
+	record.Attrs(func(attr slog.Attr) bool {
+		if this.prefix == "" && attr.Key == this.objKey {
+			obj = attr.Value.Resolve().String()
+			return true
+		}
+		fields = appendSlogAttr(fields, this.prefix, attr)
+		return true
+	})
+
+	ent := zapcore.Entry{Time: record.Time}
+	if record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		ent.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+	this.zl.logCaller(slogLevel(record.Level), obj, ent, fromContext(ctx).logFields(obj, fields...)...)
+	return nil
+}
+
+func (this *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return this
+	}
+	h := *this
+	h.fields = make([]Field, 0, len(this.fields)+len(attrs))
+	h.fields = append(h.fields, this.fields...)
+	for _, attr := range attrs {
+		if this.prefix == "" && attr.Key == this.objKey {
+			h.obj = attr.Value.Resolve().String()
+			continue
+		}
+		h.fields = appendSlogAttr(h.fields, this.prefix, attr)
+	}
+	return &h
+}
+
+func (this *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return this
+	}
+	h := *this
+	h.prefix = this.prefix + name + "."
+	return &h
+}
+
+// 将 attr 转换为 Field，group 展开为 prefix.group.key
+func appendSlogAttr(fields []Field, prefix string, attr slog.Attr) []Field {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		attrs := value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, a := range attrs {
+			fields = appendSlogAttr(fields, prefix, a)
+		}
+		return fields
+	}
+	if attr.Key == "" && value.Any() == nil {
+		return fields
+	}
+
+	key := prefix + attr.Key
+	if key == LK_OBJ {
+		key = slogObjKey
+	}
+	switch value.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(key, value.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(key, value.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(key, value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(key, value.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(key, value.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(key, value.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(key, value.Time()))
+	}
+	return append(fields, zap.Any(key, value.Any()))
+}
@@ -0,0 +1,85 @@
+package zlog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlogHandler(t *testing.T) {
+	l, dir := newTestLogger(t, &LogConfig{ObjLogLevel: map[string]Level{"NOISY": ErrorLevel}})
+	logger := slog.New(l.SlogHandler(nil))
+	logger.Info("get user", "obj", "USER", "uid", 1, slog.Group("req", "id", "r1", slog.Group("src", "ip", "10.0.0.1")))
+	logger.Debug("hidden")
+	logger.Warn("slow", "cost", 3*time.Millisecond, "ok", false)
+
+	child := logger.With("obj", "CACHE", "shard", 3).WithGroup("redis").With("db", 0)
+	child.Error("get failed", "err", errors.New("timeout"), "key", "u:1")
+	logger.Warn("ignored by obj level", "obj", "NOISY")
+
+	ctx := NewContext(context.Background(), "req-1", String("tenant", "t1"))
+	logger.InfoContext(ctx, "with ctx", "n", 2)
+
+	custom := slog.New(l.SlogHandler(&SlogOptions{ObjKey: "component", DefaultObj: "APP"}))
+	custom.Info("default obj", "obj", "not obj")
+	custom.Info("custom obj", "component", "DB", slog.Group("", "obj", 1))
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("log should have 6 lines: %s", content)
+	}
+	for i, expected := range []string{
+		"logLev=[INFO]\t\tobj=USER\tinfo=get user\tuid=1\treq.id=r1\treq.src.ip=10.0.0.1",
+		"logLev=[WARN]\t\tobj=SLOG\tinfo=slow\tcost=3ms\tok=false",
+		"logLev=[ERROR]\t\tobj=CACHE\tinfo=get failed\tshard=3\tredis.db=0\tredis.err=timeout\tredis.key=u:1",
+		"logLev=[INFO]\t\tobj=SLOG\treqId=req-1\tinfo=with ctx\tn=2\ttenant=t1",
+		"logLev=[INFO]\t\tobj=APP\tinfo=default obj\tslogObj=not obj",
+		"logLev=[INFO]\t\tobj=DB\tinfo=custom obj\tslogObj=1",
+	} {
+		if !strings.HasSuffix(lines[i], expected) || !strings.Contains(lines[i], "/slog_handler_test.go:") {
+			t.Errorf("slog line %d is error:\n%s\nexpected suffix:\n%s", i, lines[i], expected)
+		}
+	}
+	if !strings.Contains(readLogFile(t, dir, "error-test.log"), "info=get failed") {
+		t.Error("slog error should be written to error log")
+	}
+}
+
+// 默认 obj 的级别不影响 record 中指定了其他 obj 的日志
+func TestSlogHandlerObjLevel(t *testing.T) {
+	l, dir := newTestLogger(t, &LogConfig{ObjLogLevel: map[string]Level{DefaultSlogObj: ErrorLevel}})
+	logger := slog.New(l.SlogHandler(nil))
+	logger.Info("user info", "obj", "USER")
+	logger.Info("slog info")
+	logger.Info("int obj", "obj", 42)
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	if !strings.Contains(content, "obj=USER\tinfo=user info\n") {
+		t.Errorf("obj in record should be enabled: %s", content)
+	}
+	if !strings.Contains(content, "obj=42\tinfo=int obj\n") {
+		t.Errorf("obj attr should be dropped from fields: %s", content)
+	}
+	if strings.Contains(content, "info=slog info") {
+		t.Errorf("default obj should be disabled: %s", content)
+	}
+}
+
+// Logger.With 绑定的字段位于 info 之后、record 的属性之前
+func TestSlogHandlerWith(t *testing.T) {
+	l, dir := newTestLogger(t, nil)
+	logger := slog.New(l.With(String("module", "cache")).SlogHandler(nil)).With("shard", 3)
+	logger.Info("get user", "uid", 1)
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	if !strings.Contains(content, "obj=SLOG\tinfo=get user\tmodule=cache\tshard=3\tuid=1\n") {
+		t.Errorf("slog with fields is error: %s", content)
+	}
+}
@@ -0,0 +1,59 @@
+package zlog
+
+import (
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	// 堆栈默认最多记录的层数
+	defaultStackDepth = 32
+
+	// 不记录堆栈时 zapShared.stackLevel 的值，大于所有日志级别
+	stackDisabled int32 = math.MaxInt32
+)
+
+// 获取调用方的堆栈，skip 为需要跳过的层数（0 表示从调用 takeStack 的函数开始），最多 depth 层
+func takeStack(skip, depth int) string {
+	return formatFrames(callerFrames(skip+1, depth))
+}
+
+// skip 为需要跳过的层数（0 表示从调用 callerFrames 的函数开始），最多返回 depth 层
+func callerFrames(skip, depth int) []runtime.Frame {
+	pcs := make([]uintptr, depth)
+	pcs = pcs[:runtime.Callers(skip+2, pcs)]
+
+	frames := runtime.CallersFrames(pcs)
+	ret := make([]runtime.Frame, 0, len(pcs))
+	for {
+		frame, more := frames.Next()
+		ret = append(ret, frame)
+		if !more || len(ret) == depth {
+			break
+		}
+	}
+	return ret
+}
+
+// 堆栈格式同 debug.Stack()，每层占两行：
+//
+//	github.com/fevin/zlog.TestLog
+//		/path/to/zlog/log_test.go:35
+//
+// 写入日志时，换行及 tab 由 zapKVTabEncoder 转义，保证日志只占一行
+func formatFrames(frames []runtime.Frame) string {
+	var sb strings.Builder
+	for i, frame := range frames {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(frame.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(frame.File)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(frame.Line))
+	}
+	return sb.String()
+}
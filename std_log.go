@@ -0,0 +1,147 @@
+package zlog
+
+import (
+	"bytes"
+	stdlog "log"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// zlog 内部的提示信息直接输出到 stderr，不受 RedirectStdLog 影响，避免写日志时再次进入 zlog
+var internalLog = stdlog.New(os.Stderr, "", stdlog.LstdFlags)
+
+// RedirectStdLog 将标准库 log 包的输出写入默认 Logger，见 Logger.RedirectStdLog
+func RedirectStdLog(logLevel Level, obj string) (restore func()) {
+	return Default().RedirectStdLog(logLevel, obj)
+}
+
+// RedirectStdLog 将标准库 log 包（log.Print* 等）的输出以 logLevel 级别写入日志：
+// 每次输出为一条记录（多行时每行一条），去掉了标准库的前缀及时间等信息，以 obj=xxx info=xxx 的形式输出，
+// file 为调用 log.Print* 的位置，通过 log.Output(calldepth, s) 封装时按 calldepth 计算
+// 返回的 restore 用于恢复标准库 log 原来的输出、flags 及前缀；logLevel 无效时按 INFO 输出
+func (this *Logger) RedirectStdLog(logLevel Level, obj string) (restore func()) {
+	if !logLevel.valid() {
+		logLevel = InfoLevel
+	}
+	w := &stdLogWriter{zl: this.zl, logLevel: logLevel, obj: obj}
+
+	out, flags, prefix := stdlog.Writer(), stdlog.Flags(), stdlog.Prefix()
+	stdlog.SetOutput(w)
+	// 由标准库按 calldepth 计算调用位置并写在 header 中，见 stdLogCaller
+	stdlog.SetFlags(stdlog.Llongfile)
+	stdlog.SetPrefix("")
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			stdlog.SetOutput(out)
+			stdlog.SetFlags(flags)
+			stdlog.SetPrefix(prefix)
+		})
+	}
+}
+
+type stdLogWriter struct {
+	zl       zlogger
+	logLevel Level
+	obj      string
+}
+
+func (this *stdLogWriter) Write(p []byte) (int, error) {
+	flags, prefix := stdlog.Flags(), stdlog.Prefix()
+	msg, file, line := trimStdLogHeader(string(bytes.TrimRight(p, "\n")), flags, prefix)
+	caller, fatal := stdLogCaller(file, line)
+	for _, info := range strings.Split(msg, "\n") {
+		if info == "" {
+			continue
+		}
+		this.zl.logCaller(this.logLevel, this.obj, zapcore.Entry{Caller: caller},
+			zap.String(LK_OBJ, this.obj),
+			zap.String(LK_INFO, info),
+		)
+	}
+	// log.Fatal* 写完日志后会直接退出进程，需要先刷新缓冲
+	if fatal {
+		this.zl.Sync()
+	}
+	return len(p), nil
+}
+
+// 返回调用 log.Print* 的位置，及是否由 log.Fatal* 调用
+// file、line 为标准库按 calldepth 写在 header 中的位置（flags 带有 Llongfile 或 Lshortfile 时），返回调用栈中与之对应的 frame；
+// 不存在时返回跳过标准库 log 包及 stdLogWriter 后的第一个 frame
+func stdLogCaller(file string, line int) (caller zapcore.EntryCaller, fatal bool) {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	found := false
+	for {
+		frame, more := frames.Next()
+		inLog := strings.HasPrefix(frame.Function, "log.")
+		if inLog && (strings.HasPrefix(frame.Function, "log.Fatal") || strings.HasPrefix(frame.Function, "log.(*Logger).Fatal")) {
+			fatal = true
+		}
+		if !inLog && !found {
+			caller, found = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, frame.PC != 0), true
+			if file == "" {
+				return caller, fatal
+			}
+		}
+		if found && frame.Line == line && (frame.File == file || path.Base(frame.File) == file) {
+			return zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true), fatal
+		}
+		if !more {
+			if file != "" {
+				// 调用栈中找不到时，使用 header 中的位置
+				return zapcore.EntryCaller{Defined: true, File: file, Line: line}, fatal
+			}
+			return caller, fatal
+		}
+	}
+}
+
+// 去掉标准库 log 按 flags 添加的前缀、日期、时间及文件名，返回去掉后的内容及文件名中的 file、line（不存在时为空）
+// 2009/01/23 01:23:23.123123 /a/b/c/d.go:23: message
+func trimStdLogHeader(msg string, flags int, prefix string) (info, file string, line int) {
+	if flags&stdlog.Lmsgprefix == 0 {
+		msg = strings.TrimPrefix(msg, prefix)
+	}
+	if flags&stdlog.Ldate != 0 && len(msg) >= 11 {
+		msg = msg[11:]
+	}
+	if flags&(stdlog.Ltime|stdlog.Lmicroseconds) != 0 && len(msg) >= 9 {
+		msg = msg[9:]
+		if flags&stdlog.Lmicroseconds != 0 && len(msg) >= 7 {
+			msg = msg[7:]
+		}
+	}
+	if flags&(stdlog.Lshortfile|stdlog.Llongfile) != 0 {
+		if i := strings.Index(msg, ": "); i >= 0 {
+			file, line = splitFileLine(msg[:i])
+			msg = msg[i+2:]
+		}
+	}
+	if flags&stdlog.Lmsgprefix != 0 {
+		msg = strings.TrimPrefix(msg, prefix)
+	}
+	return msg, file, line
+}
+
+// 拆分 /a/b/c/d.go:23，格式不正确时 file 为空
+func splitFileLine(s string) (file string, line int) {
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return "", 0
+	}
+	line, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return "", 0
+	}
+	return s[:i], line
+}
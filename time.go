@@ -6,15 +6,6 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-// startTimeNS 单位 纳秒
-func getCost(startTimeNS int64) int64 {
-	var cost int64 = 0
-	if startTimeNS > 0 {
-		cost = time.Now().UnixNano()/1e6 - startTimeNS/1e6
-	}
-	return cost
-}
-
 func encodeTimeLayout(t time.Time, layout string, enc zapcore.PrimitiveArrayEncoder) {
 	type appendTimeEncoder interface {
 		AppendTimeLayout(time.Time, string)
@@ -0,0 +1,88 @@
+package zlog
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Timer 用于统计耗时，结束时打印带有 cost 的日志，无需手动传入 startTimeNS：
+//
+//	t := zlog.StartTimer("MYSQL", "127.0.0.1:3306")
+//	rows := query()
+//	t.Lap("query")
+//	parse(rows)
+//	t.Lap("parse")
+//	t.End(zlog.LL_INFO, "select user")
+//
+// 也可以在 defer 中使用：defer zlog.StartTimer("MYSQL", host).End(zlog.LL_INFO, "select user")
+// Timer 可以在多个 goroutine 中并发使用
+type Timer struct {
+	zl        zlogger
+	obj, host string
+	start     time.Time
+
+	mtx     sync.Mutex
+	lastLap time.Time
+	laps    []Field
+}
+
+// 开始计时，host 不为空时按 LogThirdPart 的格式打印，否则按 Log 的格式打印
+func StartTimer(obj, host string) *Timer {
+	return newTimer(logger(), obj, host)
+}
+
+// 同 zlog.StartTimer
+func (this *Logger) StartTimer(obj, host string) *Timer {
+	return newTimer(this.zl, obj, host)
+}
+
+func newTimer(zl zlogger, obj, host string) *Timer {
+	now := time.Now()
+	return &Timer{
+		zl:      zl,
+		obj:     obj,
+		host:    host,
+		start:   now,
+		lastLap: now,
+	}
+}
+
+// 记录一个阶段的耗时（距离上一次 Lap 或开始计时），结束时以 name=xxx 的形式追加在日志末尾，单位同 cost
+func (this *Timer) Lap(name string) {
+	now := time.Now()
+	this.mtx.Lock()
+	this.laps = append(this.laps, costField(name, now.Sub(this.lastLap)))
+	this.lastLap = now
+	this.mtx.Unlock()
+}
+
+// 结束计时，打印日志，cost 为开始计时至今的耗时
+func (this *Timer) End(logLevel Level, info string) {
+	this.zl.logBase(logLevel, this.obj, this.base(info), this.lapFields())
+}
+
+// 同 End，日志中带有 err=xxx，位于 cost 之后
+func (this *Timer) EndErr(logLevel Level, info string, err interface{}) {
+	this.zl.logBase(logLevel, this.obj, this.base(info, zap.Any(LK_ERR, err)), this.lapFields())
+}
+
+// 返回固定字段：obj、host（为空时省略）、info、cost 及 tail，与 Log、LogThirdPart 的格式一致
+func (this *Timer) base(info string, tail ...Field) []Field {
+	cost := time.Since(this.start)
+	base := make([]Field, 0, 4+len(tail))
+	base = append(base, zap.String(LK_OBJ, this.obj))
+	if this.host != "" {
+		base = append(base, zap.String(LK_HOST, this.host))
+	}
+	base = append(base, zap.String(LK_INFO, info), costField(LK_COST, cost))
+	return append(base, tail...)
+}
+
+// 返回各阶段耗时
+func (this *Timer) lapFields() []Field {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	return append([]Field(nil), this.laps...)
+}
@@ -0,0 +1,168 @@
+package zlog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// W3C trace context 的 header，见 https://www.w3.org/TR/trace-context/
+const TraceparentHeader = "traceparent"
+
+var errInvalidTraceparent = errors.New("zlog: invalid traceparent")
+
+// TraceContext 对应 W3C traceparent 中的 trace-id、parent-id 及 trace-flags：
+// - TraceId：32 位十六进制，整个调用链共用
+// - SpanId：16 位十六进制，当前服务处理请求的 span
+// - ParentSpanId：上游的 span，为空表示当前 span 是调用链的起点
+//
+// 通过 ContextWithTrace 存入 context 或通过 BeginRequestTrace 绑定到 Request 后，
+// LogCtx* 及 Request 打印的日志会带有 traceId、spanId、parentSpanId 字段
+type TraceContext struct {
+	TraceId      string
+	SpanId       string
+	ParentSpanId string
+	Flags        byte
+}
+
+// ParseTraceparent 解析 traceparent header，返回的 TraceContext 表示上游的 span
+// 服务端处理请求时，应使用 ParseTraceparent(h).NewChild() 生成自己的 span
+func ParseTraceparent(traceparent string) (TraceContext, error) {
+	// 00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01
+	if len(traceparent) < 55 || traceparent[2] != '-' || traceparent[35] != '-' || traceparent[52] != '-' {
+		return TraceContext{}, errInvalidTraceparent
+	}
+	version := traceparent[:2]
+	if !isLowerHex(version) || version == "ff" || (version == "00" && len(traceparent) != 55) ||
+		(len(traceparent) > 55 && traceparent[55] != '-') {
+		return TraceContext{}, errInvalidTraceparent
+	}
+	tc := TraceContext{TraceId: traceparent[3:35], SpanId: traceparent[36:52]}
+	flags := traceparent[53:55]
+	if !isLowerHex(tc.TraceId) || isZeroHex(tc.TraceId) || !isLowerHex(tc.SpanId) || isZeroHex(tc.SpanId) || !isLowerHex(flags) {
+		return TraceContext{}, errInvalidTraceparent
+	}
+	b, _ := hex.DecodeString(flags)
+	tc.Flags = b[0]
+	return tc, nil
+}
+
+// NewTraceContext 生成新的调用链，flags 为 01（sampled）
+func NewTraceContext() TraceContext {
+	return TraceContext{TraceId: randomHex(16), SpanId: randomHex(8), Flags: 0x01}
+}
+
+// NewChild 返回同一调用链中的子 span，当前 span 作为子 span 的 ParentSpanId
+// tc 无效时等同于 NewTraceContext
+func (tc TraceContext) NewChild() TraceContext {
+	if !tc.Valid() {
+		return NewTraceContext()
+	}
+	return TraceContext{TraceId: tc.TraceId, SpanId: randomHex(8), ParentSpanId: tc.SpanId, Flags: tc.Flags}
+}
+
+// TraceId、SpanId 均不为空时有效
+func (tc TraceContext) Valid() bool {
+	return tc.TraceId != "" && tc.SpanId != ""
+}
+
+// Traceparent 返回调用下游时需要传递的 traceparent header，tc 无效时返回空字符串
+func (tc TraceContext) Traceparent() string {
+	if !tc.Valid() {
+		return ""
+	}
+	return "00-" + tc.TraceId + "-" + tc.SpanId + "-" + hex.EncodeToString([]byte{tc.Flags})
+}
+
+// Fields 返回 traceId、spanId、parentSpanId（为空时省略）字段，tc 无效时返回 nil
+func (tc TraceContext) Fields() []Field {
+	if !tc.Valid() {
+		return nil
+	}
+	fields := []Field{
+		zap.String(LK_TRACE_ID, tc.TraceId),
+		zap.String(LK_SPAN_ID, tc.SpanId),
+	}
+	if tc.ParentSpanId != "" {
+		fields = append(fields, zap.String(LK_PARENT_SPAN_ID, tc.ParentSpanId))
+	}
+	return fields
+}
+
+// Request 的 trace 字段之后带有该字段，reqFields 据此将其之前的字段移到 reqId 之后
+// SkipType 的字段不会被编码
+var traceFieldsMark = zap.Field{Key: "zlog.traceFields", Type: zapcore.SkipType}
+
+// 拼装 LogReq* 的日志字段：obj、reqId、trace 字段、base、With 绑定的字段、其余的 fields
+// fields 中 traceFieldsMark 及之前的字段（见 Request）为 trace 字段，移到 reqId 之后，与 LogCtx* 中 trace 字段的位置一致
+func reqFields(obj, reqId string, fields []Field, base ...Field) []Field {
+	n := 0
+	for i := range fields {
+		if fields[i].Type == zapcore.SkipType && fields[i].Key == traceFieldsMark.Key {
+			n = i + 1
+			break
+		}
+	}
+	ret := make([]Field, 0, 3+len(base)+len(fields))
+	ret = append(ret, zap.String(LK_OBJ, obj), zap.String(LK_REQ_ID, reqId))
+	ret = append(ret, fields[:n]...)
+	ret = append(ret, base...)
+	if n == len(fields) {
+		return ret
+	}
+	ret = append(ret, withFieldsMark)
+	return append(ret, fields[n:]...)
+}
+
+// ContextWithTrace 返回携带 tc 的 context，ctx 中已有的 reqId 及 fields 保持不变
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	v := &ctxValue{trace: tc}
+	if parent := fromContext(ctx); parent != nil {
+		v.reqId = parent.reqId
+		v.fields = parent.fields
+	}
+	return context.WithValue(ctx, ctxKey{}, v)
+}
+
+// 获取 ctx 中携带的 TraceContext，不存在时返回 false
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	if v := fromContext(ctx); v != nil && v.trace.Valid() {
+		return v.trace, true
+	}
+	return TraceContext{}, false
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	for {
+		rand.Read(b)
+		for _, c := range b {
+			if c != 0 {
+				return hex.EncodeToString(b)
+			}
+		}
+	}
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZeroHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '0' {
+			return false
+		}
+	}
+	return true
+}
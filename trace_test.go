@@ -0,0 +1,90 @@
+package zlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tc, err := ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	if err != nil || tc.TraceId != "0af7651916cd43dd8448eb211c80319c" || tc.SpanId != "b7ad6b7169203331" || tc.Flags != 0x01 {
+		t.Fatalf("parse traceparent is error: %+v %v", tc, err)
+	}
+	if tp := tc.Traceparent(); tp != "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01" {
+		t.Errorf("traceparent is error: %s", tp)
+	}
+	if _, err := ParseTraceparent("01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00-future"); err != nil {
+		t.Errorf("higher version with extra fields should be accepted: %v", err)
+	}
+	for _, invalid := range []string{
+		"",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331",
+		"00-0AF7651916CD43DD8448EB211C80319C-b7ad6b7169203331-01",
+		"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-0000000000000000-01",
+		"ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-extra",
+		"00-0af7651916cd43dd8448eb211c80319g-b7ad6b7169203331-01",
+	} {
+		if _, err := ParseTraceparent(invalid); err == nil {
+			t.Errorf("traceparent %q should be invalid", invalid)
+		}
+	}
+
+	child := tc.NewChild()
+	if child.TraceId != tc.TraceId || child.ParentSpanId != tc.SpanId || child.SpanId == tc.SpanId || len(child.SpanId) != 16 {
+		t.Errorf("child span is error: %+v", child)
+	}
+	root := TraceContext{}.NewChild()
+	if !root.Valid() || root.ParentSpanId != "" || len(root.TraceId) != 32 {
+		t.Errorf("root span is error: %+v", root)
+	}
+	if _, err := ParseTraceparent(root.Traceparent()); err != nil {
+		t.Errorf("generated traceparent should be valid: %s %v", root.Traceparent(), err)
+	}
+}
+
+func TestTraceLog(t *testing.T) {
+	l, dir := newTestLogger(t, nil)
+	parent, _ := ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	tc := parent.NewChild()
+	traceKV := "traceId=0af7651916cd43dd8448eb211c80319c\tspanId=" + tc.SpanId + "\tparentSpanId=b7ad6b7169203331"
+
+	ctx := ContextWithTrace(NewContext(context.Background(), "req-1", String("uid", "1")), tc)
+	ctx = NewContext(ctx, "", String("shard", "3"))
+	if got, isOK := TraceFromContext(ctx); !isOK || got != tc {
+		t.Errorf("trace from context is error: %+v", got)
+	}
+	l.LogCtx(ctx, LL_INFO, "USER", "get user")
+
+	r := l.BeginRequestTrace("req-2", "10.0.0.1", "/user", "", tc)
+	r.Log(LL_INFO, "USER", "get user", Int("n", 1))
+	r.Logf(LL_INFO, "USER", "get %d users", 2)
+	r.Logf(LL_DEBUG, "USER", "get %d users", 3)
+	r.Log(LL_INFO, "USER", "user span", String(LK_SPAN_ID, "user-span"))
+	l.LogReqw(LL_INFO, "USER", "req-3", "user span", String(LK_SPAN_ID, "user-span"))
+	l.LogCtx(r.Context(context.Background()), LL_INFO, "CACHE", "miss")
+	r.End(200, "ok")
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	for _, expected := range []string{
+		"obj=USER\treqId=req-1\t" + traceKV + "\tinfo=get user\tuid=1\tshard=3\n",
+		"obj=RB\treqId=req-2\t" + traceKV + "\treqClientIP=10.0.0.1\treqUri=/user\treqParams=\tcost=0\n",
+		"obj=USER\treqId=req-2\t" + traceKV + "\tinfo=get user\tn=1\n",
+		"obj=USER\treqId=req-2\t" + traceKV + "\tinfo=get 2 users\n",
+		"obj=CACHE\treqId=req-2\t" + traceKV + "\tinfo=miss\n",
+		// 调用方传入的同名字段不会被当作 trace 字段
+		"obj=USER\treqId=req-2\t" + traceKV + "\tinfo=user span\tspanId=user-span\n",
+		"obj=USER\treqId=req-3\tinfo=user span\tspanId=user-span\n",
+		"obj=RE\treqId=req-2\t" + traceKV + "\tretCode=200\tretData=ok\tcost=",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("log not found: %q in %s", expected, content)
+		}
+	}
+	if strings.Contains(content, "get 3 users") || strings.Count(content, traceKV) != 7 {
+		t.Errorf("trace log is error: %s", content)
+	}
+}
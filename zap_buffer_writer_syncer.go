@@ -5,7 +5,6 @@ import (
 	"bytes"
 	"context"
 	"io"
-	stdlog "log"
 	"sync"
 	"time"
 
@@ -47,25 +46,28 @@ func newBufferWriteSyncer(ws zapcore.WriteSyncer, bufferSize int, flushInterval
 
 	ctx, cancel := context.WithCancel(context.Background())
 	lws := &lockedWriteSyncer{
-		ws:        ws,
-		bsBufPool: NewBytesBufferPool(1024),
-		bsBufChan: make(chan *bytes.Buffer, defaultAsyncBufferSize),
-		ctx:       ctx,
-		ctxCancel: cancel,
+		ws:           ws,
+		bsBufPool:    NewBytesBufferPool(1024),
+		bsBufChan:    make(chan asyncItem, defaultAsyncBufferSize),
+		ctx:          ctx,
+		ctxCancel:    cancel,
+		consumerDone: make(chan struct{}),
 	}
 
 	go lws.consume()
 
-	// flush buffer every interval
-	// we do not need exit this goroutine explicitly
+	// flush buffer every interval, exit when the syncer is closed
 	go func() {
 		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-ticker.C:
 				if err := lws.Sync(); err != nil {
 					return
 				}
+			case <-ctx.Done():
+				return
 			}
 		}
 	}()
@@ -97,13 +99,26 @@ type lockedWriteSyncer struct {
 	sync.Mutex
 	ws        zapcore.WriteSyncer
 	bsBufPool *BytesBufferPool
-	bsBufChan chan *bytes.Buffer
+	bsBufChan chan asyncItem
 	ctx       context.Context
 	ctxCancel context.CancelFunc
+
+	// closed when consume exit
+	consumerDone chan struct{}
+}
+
+// asyncItem is either the data to write, or a Sync request if synced is not nil.
+// Sync requests go through the same channel as the data, so that the consumer
+// flushes exactly the data queued before them, in order
+type asyncItem struct {
+	bsBuf  *bytes.Buffer
+	synced chan error
 }
 
 func (s *lockedWriteSyncer) Close() error {
 	s.ctxCancel()
+	// wait for consume exit, so that no data would be written after the final Sync
+	<-s.consumerDone
 	s.cleanBufChan()
 	return s.Sync()
 }
@@ -113,11 +128,14 @@ func (s *lockedWriteSyncer) cleanBufChan() {
 	timer := time.NewTimer(tm)
 	for {
 		select {
-		case bsBuf, _ := <-s.bsBufChan:
-			if bsBuf == nil {
+		case item, isOK := <-s.bsBufChan:
+			if !isOK {
 				goto CLEAN_EXIT
 			}
-			s.doWrite(bsBuf)
+			// Sync requests are left to Sync itself, see Sync
+			if item.synced == nil {
+				s.doWrite(item.bsBuf)
+			}
 		case <-timer.C:
 			goto CLEAN_EXIT
 		}
@@ -128,16 +146,26 @@ CLEAN_EXIT:
 }
 
 func (s *lockedWriteSyncer) consume() {
+	defer close(s.consumerDone)
+	// the first write error since the last Sync, returned by the next Sync
+	var err error
 	for {
 		select {
-		case bsBuf, isOK := <-s.bsBufChan:
-			if bsBuf == nil && !isOK {
-				stdlog.Println("[zlog] channel close, zlog async consume exit!")
+		case item, isOK := <-s.bsBufChan:
+			if !isOK {
+				internalLog.Println("[zlog] channel close, zlog async consume exit!")
 				return
 			}
-			s.doWrite(bsBuf)
+			if item.synced != nil {
+				item.synced <- s.sync(err)
+				err = nil
+				continue
+			}
+			if _, werr := s.doWrite(item.bsBuf); err == nil {
+				err = werr
+			}
 		case <-s.ctx.Done():
-			stdlog.Println("[zlog] context done, zlog async consume exit!")
+			internalLog.Println("[zlog] context done, zlog async consume exit!")
 			return
 		}
 	}
@@ -147,7 +175,7 @@ func (s *lockedWriteSyncer) Write(bs []byte) (int, error) {
 	bsBuf := s.bsBufPool.Get()
 	bsBuf.Write(bs)
 	select {
-	case s.bsBufChan <- bsBuf:
+	case s.bsBufChan <- asyncItem{bsBuf: bsBuf}:
 		return len(bs), nil
 	default:
 		return s.doWrite(bsBuf)
@@ -167,9 +195,59 @@ func (s *lockedWriteSyncer) doWrite(bsBuf *bytes.Buffer) (int, error) {
 	return n, err
 }
 
-func (s *lockedWriteSyncer) Sync() error {
+// flush buffer, return err if not nil, otherwise the error of flushing
+func (s *lockedWriteSyncer) sync(err error) error {
 	s.Lock()
-	err := s.ws.Sync()
-	s.Unlock()
+	defer s.Unlock()
+	if serr := s.ws.Sync(); err == nil {
+		err = serr
+	}
 	return err
 }
+
+// write the data queued before Sync, then flush buffer, return the first error.
+// the queued data is written by the consumer, so that it would not be reordered
+func (s *lockedWriteSyncer) Sync() error {
+	select {
+	case <-s.consumerDone:
+		return s.syncQueued()
+	default:
+	}
+
+	synced := make(chan error, 1)
+	select {
+	case s.bsBufChan <- asyncItem{synced: synced}:
+	case <-s.consumerDone:
+		return s.syncQueued()
+	}
+	select {
+	case err := <-synced:
+		return err
+	case <-s.consumerDone:
+		return s.syncQueued()
+	}
+}
+
+// write the data queued in bsBufChan and flush buffer, only used after consume exit
+func (s *lockedWriteSyncer) syncQueued() error {
+	s.Lock()
+	defer s.Unlock()
+	var err error
+	for {
+		select {
+		case item := <-s.bsBufChan:
+			if item.synced != nil {
+				continue
+			}
+			if _, werr := s.ws.Write(item.bsBuf.Bytes()); err == nil {
+				err = werr
+			}
+			s.bsBufPool.Put(item.bsBuf)
+		default:
+			if serr := s.ws.Sync(); err == nil {
+				err = serr
+			}
+			return err
+		}
+	}
+}
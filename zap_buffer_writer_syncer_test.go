@@ -0,0 +1,73 @@
+package zlog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 记录写入内容的 WriteSyncer，每次 Write 稍作停顿，使消费者与 Sync 交错执行
+type slowWriteSyncer struct {
+	sync.Mutex
+	buf bytes.Buffer
+	err error
+}
+
+func (this *slowWriteSyncer) Write(bs []byte) (int, error) {
+	time.Sleep(10 * time.Microsecond)
+	this.Lock()
+	defer this.Unlock()
+	if this.err != nil {
+		return 0, this.err
+	}
+	return this.buf.Write(bs)
+}
+
+func (this *slowWriteSyncer) Sync() error {
+	return nil
+}
+
+func TestBufferWriteSyncerOrder(t *testing.T) {
+	ws := &slowWriteSyncer{}
+	lws, closer := newBufferWriteSyncer(ws, 64, time.Hour)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			lws.Sync()
+		}
+	}()
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(lws, "%d\n", i)
+	}
+	<-done
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(ws.buf.String(), "\n"), "\n")
+	if len(lines) != 2000 {
+		t.Fatalf("line count should be 2000, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if line != fmt.Sprint(i) {
+			t.Fatalf("line %d is out of order: %s", i, line)
+		}
+	}
+}
+
+func TestBufferWriteSyncerErr(t *testing.T) {
+	ws := &slowWriteSyncer{err: errors.New("disk full")}
+	lws, closer := newBufferWriteSyncer(ws, 64, time.Hour)
+	defer closer.Close()
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(lws, "%d\n", i)
+	}
+	if err := lws.Sync(); err == nil || err.Error() != "disk full" {
+		t.Errorf("Sync should return the write error, got %v", err)
+	}
+}
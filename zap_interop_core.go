@@ -0,0 +1,88 @@
+package zlog
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ZapCore 返回默认 Logger 的 zapcore.Core，见 Logger.ZapCore
+func ZapCore() zapcore.Core {
+	return Default().ZapCore()
+}
+
+// ZapLogger 返回写入默认 Logger 的 *zap.Logger，见 Logger.ZapLogger
+func ZapLogger(opts ...zap.Option) *zap.Logger {
+	return Default().ZapLogger(opts...)
+}
+
+// ZapCore 返回写入当前 Logger（program.log 及 error-program.log）的 zapcore.Core，供只接受 zap 的第三方库使用：
+// - zap 的 Message 作为 info 输出，logLev 与 Log* 一致，如 [INFO]；DPanic、Panic 按 ERROR 输出
+// - zap Logger 的名字（zap.Logger.Named）作为 obj 输出，未命名时为 ZAP，并按 obj 应用 LogConfig.ObjLogLevel
+// - Reload、SetLevel 等同样生效
+func (this *Logger) ZapCore() zapcore.Core {
+	return this.zl.zapCore()
+}
+
+// ZapLogger 返回基于 ZapCore 的 *zap.Logger，已开启 zap.AddCaller，file 为调用 zap 的位置
+func (this *Logger) ZapLogger(opts ...zap.Option) *zap.Logger {
+	return zap.New(this.ZapCore(), append([]zap.Option{zap.AddCaller()}, opts...)...)
+}
+
+// zapInteropCore 将 zap 原生的日志转换为 Log* 的格式后写入 core
+type zapInteropCore struct {
+	core   zapcore.Core
+	filter *levelFilter
+}
+
+func (c *zapInteropCore) Enabled(lvl zapcore.Level) bool {
+	return c.core.Enabled(lvl)
+}
+
+func (c *zapInteropCore) With(fields []zapcore.Field) zapcore.Core {
+	return &zapInteropCore{core: c.core.With(fields), filter: c.filter}
+}
+
+func (c *zapInteropCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	level := levelOfZapEntry(ent.Level)
+	if c.Enabled(ent.Level) && (level >= ErrorLevel || c.filter.objEnabled(level, zapEntryObj(ent))) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// 转换后重新 Check，由 core 按级别决定写入哪些日志文件；obj 级别只作用于 program.log
+func (c *zapInteropCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	level := levelOfZapEntry(ent.Level)
+	obj := zapEntryObj(ent)
+	info := ent.Message
+	ent.Message = level.tag()
+	if ce := c.core.Check(ent, nil); ce != nil {
+		fields = appendFields([]Field{
+			zap.String(LK_OBJ, obj),
+			zap.String(LK_INFO, info),
+		}, fields)
+		if !c.filter.objEnabled(level, obj) {
+			fields = append(fields, skipProgramLogField)
+		}
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+func (c *zapInteropCore) Sync() error {
+	return c.core.Sync()
+}
+
+func levelOfZapEntry(lvl zapcore.Level) Level {
+	if lvl == zapcore.DPanicLevel || lvl == zapcore.PanicLevel {
+		return ErrorLevel
+	}
+	return levelOfZapLevel(lvl)
+}
+
+func zapEntryObj(ent zapcore.Entry) string {
+	if ent.LoggerName != "" {
+		return ent.LoggerName
+	}
+	return OBJ_ZAP
+}
@@ -0,0 +1,42 @@
+package zlog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestZapLogger(t *testing.T) {
+	l, dir := newTestLogger(t, &LogConfig{ObjLogLevel: map[string]Level{"NOISY": ErrorLevel}})
+	zl := l.With(String("app", "demo")).ZapLogger()
+	zl.Info("connected", zap.String("host", "127.0.0.1"))
+	zl.Debug("hidden")
+	zl.Named("MYSQL").Error("query failed", zap.Error(errors.New("timeout")))
+	zl.Named("NOISY").Warn("ignored by obj level")
+	zl.Sugar().Warnw("sugar", "n", 1)
+	l.SetLevel(DebugLevel)
+	zl.With(zap.Int("shard", 3)).Debug("visible")
+	l.Close()
+
+	content := readLogFile(t, dir, "test.log")
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("log should have 4 lines: %s", content)
+	}
+	for i, expected := range []string{
+		"logLev=[INFO]\t\tobj=ZAP\tinfo=connected\tapp=demo\thost=127.0.0.1",
+		"logLev=[ERROR]\t\tobj=MYSQL\tinfo=query failed\tapp=demo\terror=timeout",
+		"logLev=[WARN]\t\tobj=ZAP\tinfo=sugar\tapp=demo\tn=1",
+		"logLev=[DEBUG]\t\tobj=ZAP\tinfo=visible\tapp=demo\tshard=3",
+	} {
+		if !strings.HasSuffix(lines[i], expected) || !strings.Contains(lines[i], "/zap_interop_core_test.go:") {
+			t.Errorf("zap line %d is error:\n%s\nexpected suffix:\n%s", i, lines[i], expected)
+		}
+	}
+	errContent := readLogFile(t, dir, "error-test.log")
+	if strings.Count(errContent, "\n") != 1 || !strings.Contains(errContent, "info=query failed") {
+		t.Errorf("error log is error: %s", errContent)
+	}
+}
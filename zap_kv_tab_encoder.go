@@ -27,22 +27,25 @@ func putZapKVTabEncoder(enc *zapKVTabEncoder) {
 		enc.reflectBuf.Free()
 	}
 	enc.EncoderConfig = nil
+	enc.costUnit = ""
 	enc.buf = nil
 	enc.reflectBuf = nil
 	enc.reflectEnc = nil
 	_zapKVTabPool.Put(enc)
 }
 
-func newZapKVTabEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+func newZapKVTabEncoder(cfg zapcore.EncoderConfig, costUnit string) zapcore.Encoder {
 	return &zapKVTabEncoder{
 		EncoderConfig: &cfg,
+		costUnit:      costUnit,
 		buf:           _bufferPool.Get(),
 	}
 }
 
 type zapKVTabEncoder struct {
 	*zapcore.EncoderConfig
-	buf *buffer.Buffer
+	costUnit string // 耗时字段的输出单位
+	buf      *buffer.Buffer
 
 	// for encoding generic values by reflection
 	reflectBuf *buffer.Buffer
@@ -122,6 +125,10 @@ func (enc *zapKVTabEncoder) encodeReflected(obj interface{}) ([]byte, error) {
 }
 
 func (enc *zapKVTabEncoder) AddReflected(key string, obj interface{}) error {
+	if d, ok := obj.(costDuration); ok {
+		enc.addCost(key, time.Duration(d))
+		return nil
+	}
 	valueBytes, err := enc.encodeReflected(obj)
 	if err != nil {
 		return err
@@ -131,11 +138,28 @@ func (enc *zapKVTabEncoder) AddReflected(key string, obj interface{}) error {
 	return err
 }
 
+// addCost 按 costUnit 输出耗时，负数（如系统时间回拨）输出 0，并通过 <key>Negative 记录原始值
+func (enc *zapKVTabEncoder) addCost(key string, d time.Duration) {
+	enc.addKey(key)
+	if d >= 0 {
+		enc.buf.Write(appendCost(nil, enc.costUnit, d))
+		return
+	}
+	enc.buf.AppendInt(0)
+	enc.addKey(key + "Negative")
+	enc.buf.Write(appendCost(nil, enc.costUnit, d))
+}
+
 func (enc *zapKVTabEncoder) OpenNamespace(_ string) {
 }
 
 func (enc *zapKVTabEncoder) AddString(key, val string) {
 	enc.addKey(key)
+	if key == enc.StacktraceKey {
+		// stack is multi-line, keep the log in one line
+		enc.appendOneLineString(val)
+		return
+	}
 	enc.AppendString(val)
 }
 
@@ -257,6 +281,7 @@ func (enc *zapKVTabEncoder) Clone() zapcore.Encoder {
 func (enc *zapKVTabEncoder) clone() *zapKVTabEncoder {
 	clone := getZapKVTabEncoder()
 	clone.EncoderConfig = enc.EncoderConfig
+	clone.costUnit = enc.costUnit
 	clone.buf = _bufferPool.Get()
 	return clone
 }
@@ -287,10 +312,24 @@ func (enc *zapKVTabEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Fiel
 	// white space required!!
 	final.buf.AppendByte('\t')
 
-	// default dltag
+	// fields bound by With, encoded already, are written at withFieldsMark (after the fixed fields),
+	// or before the stack if there is no mark, or at the end
+	withWritten := false
 	for i := range fields {
+		if !withWritten && (isWithFieldsMark(fields[i]) || fields[i].Key == final.StacktraceKey) {
+			final.buf.Write(enc.buf.Bytes())
+			withWritten = true
+		}
 		fields[i].AddTo(final)
 	}
+	if !withWritten {
+		final.buf.Write(enc.buf.Bytes())
+	}
+
+	// stack added by zap.AddStacktrace
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.AddString(final.StacktraceKey, ent.Stack)
+	}
 	final.buf.AppendString(enc.LineEnding)
 
 	ret := final.buf
@@ -312,6 +351,29 @@ func (enc *zapKVTabEncoder) addElementSeparator() {
 	enc.buf.AppendByte('\t')
 }
 
+// escape \n \r \t as \\n \\r \\t
+func (enc *zapKVTabEncoder) appendOneLineString(val string) {
+	last := 0
+	for i := 0; i < len(val); i++ {
+		var esc byte
+		switch val[i] {
+		case '\n':
+			esc = 'n'
+		case '\r':
+			esc = 'r'
+		case '\t':
+			esc = 't'
+		default:
+			continue
+		}
+		enc.buf.AppendString(val[last:i])
+		enc.buf.AppendByte('\\')
+		enc.buf.AppendByte(esc)
+		last = i + 1
+	}
+	enc.buf.AppendString(val[last:])
+}
+
 func (enc *zapKVTabEncoder) appendFloat(val float64, bitSize int) {
 	switch {
 	case math.IsNaN(val):
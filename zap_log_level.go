@@ -2,26 +2,54 @@ package zlog
 
 import (
 	"fmt"
+
 	"go.uber.org/zap/zapcore"
 )
 
-var (
-	zapLevelMap map[int8]zapcore.Level
-)
+// 配置中 MaxLogLevel 对应的 zap level
+func getZapLevel(level Level) zapcore.Level {
+	switch level {
+	case DebugLevel:
+		return zapcore.DebugLevel
+	case InfoLevel:
+		return zapcore.InfoLevel
+	case WarnLevel:
+		return zapcore.WarnLevel
+	case ErrorLevel:
+		return zapcore.ErrorLevel
+	case FatalLevel:
+		return zapcore.FatalLevel
+	}
 
-func init() {
-	zapLevelMap = make(map[int8]zapcore.Level, 5)
-	zapLevelMap[-1] = zapcore.DebugLevel
-	zapLevelMap[0] = zapcore.InfoLevel
-	zapLevelMap[1] = zapcore.WarnLevel
-	zapLevelMap[2] = zapcore.ErrorLevel
-	zapLevelMap[3] = zapcore.FatalLevel
+	panic(fmt.Sprintf("zlog level is error: the level[%d] doesnot exist!", level))
 }
 
-func getZapLevel(level int8) zapcore.Level {
-	if zapLevel, isOK := zapLevelMap[level]; isOK {
-		return zapLevel
+// getZapLevel 的逆操作
+func levelOfZapLevel(zapLevel zapcore.Level) Level {
+	switch zapLevel {
+	case zapcore.DebugLevel:
+		return DebugLevel
+	case zapcore.InfoLevel:
+		return InfoLevel
+	case zapcore.WarnLevel:
+		return WarnLevel
+	case zapcore.ErrorLevel:
+		return ErrorLevel
 	}
+	return FatalLevel
+}
 
-	panic(fmt.Sprintf("zlog level is error: the level[%d] doesnot exist!", level))
+// 打印日志时 level 对应的 zap level，FATAL 按 ERROR 输出（zap FATAL will exec os.Exit）
+func getZapLogLevel(level Level) (zapcore.Level, bool) {
+	switch level {
+	case DebugLevel:
+		return zapcore.DebugLevel, true
+	case InfoLevel:
+		return zapcore.InfoLevel, true
+	case WarnLevel:
+		return zapcore.WarnLevel, true
+	case ErrorLevel, FatalLevel:
+		return zapcore.ErrorLevel, true
+	}
+	return zapcore.InfoLevel, false
 }
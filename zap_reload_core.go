@@ -0,0 +1,180 @@
+package zlog
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// reloadCore 相当于 zapcore.NewTee，区别在于内部的 cores 可以在运行时整体替换（见 reload）
+//
+// 当前的 cores 以 coreSet 快照的形式发布，Enabled、Write 不需要加锁；
+// Write 期间持有 coreSet 的引用，reload 替换快照后等待旧 coreSet 的引用全部释放，
+// 因此 reload 返回前，所有写往旧 cores 的日志都已经进入旧 writer 的队列，
+// 随后关闭旧 writer 时会把队列中的日志全部写入旧文件，不会丢失也不会重复
+type reloadCore struct {
+	state *reloadState
+
+	// With 绑定的 fields，以及基于当前 cores 预先编码好的子 cores
+	fields []Field
+	cache  atomic.Value // *reloadCoreCache
+}
+
+type reloadState struct {
+	mu      sync.Mutex   // 串行执行 reload、Close
+	current atomic.Value // *coreSet
+}
+
+// coreSet 是某一时刻的 cores 及其 writer
+type coreSet struct {
+	cores  []zapcore.Core
+	closer io.Closer
+	gen    uint64
+
+	// 引用计数：发布期间持有 1 个引用，每个进行中的 Write、Sync 各持有 1 个
+	// 降为 0 后不再增加，并关闭 drained
+	refs    int64
+	drained chan struct{}
+}
+
+type reloadCoreCache struct {
+	gen   uint64
+	cores []zapcore.Core
+}
+
+func newCoreSet(cores []zapcore.Core, closer io.Closer, gen uint64) *coreSet {
+	return &coreSet{
+		cores:   cores,
+		closer:  closer,
+		gen:     gen,
+		refs:    1,
+		drained: make(chan struct{}),
+	}
+}
+
+func newReloadCore(cores []zapcore.Core, closer io.Closer) *reloadCore {
+	state := new(reloadState)
+	state.current.Store(newCoreSet(cores, closer, 0))
+	return &reloadCore{state: state}
+}
+
+func (s *reloadState) load() *coreSet {
+	return s.current.Load().(*coreSet)
+}
+
+// 获取当前 coreSet 的引用，使用完后需调用 release
+func (s *reloadState) acquire() *coreSet {
+	for {
+		set := s.load()
+		n := atomic.LoadInt64(&set.refs)
+		// 引用已降为 0，说明 set 已被替换，重新获取
+		if n > 0 && atomic.CompareAndSwapInt64(&set.refs, n, n+1) {
+			return set
+		}
+	}
+}
+
+func (set *coreSet) release() {
+	if atomic.AddInt64(&set.refs, -1) == 0 {
+		close(set.drained)
+	}
+}
+
+// 替换 cores，等待旧 cores 上进行中的 Write 结束后，关闭旧 cores 的 writer
+func (c *reloadCore) reload(cores []zapcore.Core, closer io.Closer) error {
+	s := c.state
+	s.mu.Lock()
+	old := s.load()
+	s.current.Store(newCoreSet(cores, closer, old.gen+1))
+	s.mu.Unlock()
+
+	old.release()
+	<-old.drained
+	return old.closer.Close()
+}
+
+// 关闭当前 cores 的 writer
+func (c *reloadCore) Close() error {
+	s := c.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load().closer.Close()
+}
+
+// 返回 set 中绑定了 fields 的 cores
+func (c *reloadCore) cores(set *coreSet) []zapcore.Core {
+	if len(c.fields) == 0 {
+		return set.cores
+	}
+	if cache, _ := c.cache.Load().(*reloadCoreCache); cache != nil && cache.gen == set.gen {
+		return cache.cores
+	}
+
+	cores := make([]zapcore.Core, len(set.cores))
+	for i := range set.cores {
+		cores[i] = set.cores[i].With(c.fields)
+	}
+	c.cache.Store(&reloadCoreCache{gen: set.gen, cores: cores})
+	return cores
+}
+
+func (c *reloadCore) Enabled(lvl zapcore.Level) bool {
+	for _, core := range c.state.load().cores {
+		if core.Enabled(lvl) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *reloadCore) With(fields []Field) zapcore.Core {
+	if len(fields) == 0 {
+		return c
+	}
+	clone := &reloadCore{
+		state:  c.state,
+		fields: make([]Field, 0, len(c.fields)+len(fields)),
+	}
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+
+	// encode fields once here
+	clone.cores(c.state.load())
+	return clone
+}
+
+func (c *reloadCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *reloadCore) Write(ent zapcore.Entry, fields []Field) error {
+	set := c.state.acquire()
+	defer set.release()
+	var err error
+	for _, core := range c.cores(set) {
+		if !core.Enabled(ent.Level) {
+			continue
+		}
+		if e := core.Write(ent, fields); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (c *reloadCore) Sync() error {
+	set := c.state.acquire()
+	defer set.release()
+	var err error
+	for _, core := range set.cores {
+		if e := core.Sync(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
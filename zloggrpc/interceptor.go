@@ -0,0 +1,286 @@
+// zloggrpc 提供 gRPC 的拦截器，日志格式与 zloghttp 保持一致：
+//
+//	// 服务端：每个请求打印 RB/RE 日志
+//	s := grpc.NewServer(
+//		grpc.UnaryInterceptor(zloggrpc.UnaryServerInterceptor(nil)),
+//		grpc.StreamInterceptor(zloggrpc.StreamServerInterceptor(nil)),
+//	)
+//
+//	// 客户端：每次调用打印一条 LogReqThirdPart 日志
+//	conn, err := grpc.Dial(target,
+//		grpc.WithUnaryInterceptor(zloggrpc.UnaryClientInterceptor(nil)),
+//		grpc.WithStreamInterceptor(zloggrpc.StreamClientInterceptor(nil)),
+//	)
+//
+// 服务端从 incoming metadata 中读取 reqId（不存在或不合法时生成新的 reqId）并存入 context，
+// handler 中可以通过 zlog.LogCtx* 打印带有 reqId 的日志；
+// 客户端从 context 中取出 reqId（见 zlog.NewContext）写入 outgoing metadata，向下游传递
+package zloggrpc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fevin/zlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultReqIdKey  = "x-request-id"
+	DefaultClientObj = "GRPC"
+)
+
+type Options struct {
+	// 打印日志使用的 Logger，为 nil 时使用 zlog 的默认 Logger
+	Logger *zlog.Logger
+
+	// 传递 reqId 的 metadata key，默认为 x-request-id
+	ReqIdKey string
+
+	// 服务端生成 reqId 的方法，默认为 zlog.NewReqID；metadata 中的 reqId 不合法（见 zlog.NormalizeReqID）时同样重新生成
+	GenReqId func(ctx context.Context) string
+
+	// 根据状态码选择 RE 日志及客户端日志的级别，默认见 DefaultCodeLevel
+	CodeLevel func(code codes.Code) zlog.Level
+
+	// 客户端日志的 obj，默认为 GRPC
+	ClientObj string
+}
+
+type interceptor struct {
+	logger    *zlog.Logger
+	reqIdKey  string
+	genReqId  func(ctx context.Context) string
+	codeLevel func(code codes.Code) zlog.Level
+	clientObj string
+}
+
+func newInterceptor(opts *Options) *interceptor {
+	if opts == nil {
+		opts = new(Options)
+	}
+	i := &interceptor{
+		logger:    opts.Logger,
+		reqIdKey:  opts.ReqIdKey,
+		genReqId:  opts.GenReqId,
+		codeLevel: opts.CodeLevel,
+		clientObj: opts.ClientObj,
+	}
+	if i.reqIdKey == "" {
+		i.reqIdKey = DefaultReqIdKey
+	}
+	if i.genReqId == nil {
+		i.genReqId = func(context.Context) string { return zlog.NewReqID() }
+	}
+	if i.codeLevel == nil {
+		i.codeLevel = DefaultCodeLevel
+	}
+	if i.clientObj == "" {
+		i.clientObj = DefaultClientObj
+	}
+	return i
+}
+
+// DefaultCodeLevel 默认的状态码级别：
+// OK 为 INFO；Unknown、DeadlineExceeded、Unimplemented、Internal、Unavailable、DataLoss 为 ERROR；其他为 WARN
+func DefaultCodeLevel(code codes.Code) zlog.Level {
+	switch code {
+	case codes.OK:
+		return zlog.LL_INFO
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return zlog.LL_ERROR
+	}
+	return zlog.LL_WARN
+}
+
+// UnaryServerInterceptor 返回服务端的 unary 拦截器，opts 为 nil 时使用默认配置
+func UnaryServerInterceptor(opts *Options) grpc.UnaryServerInterceptor {
+	i := newInterceptor(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, end := i.begin(ctx, info.FullMethod)
+		defer recoverEnd(end)
+		resp, err := handler(ctx, req)
+		end(err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor 返回服务端的 stream 拦截器，opts 为 nil 时使用默认配置
+func StreamServerInterceptor(opts *Options) grpc.StreamServerInterceptor {
+	i := newInterceptor(opts)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, end := i.begin(ss.Context(), info.FullMethod)
+		defer recoverEnd(end)
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		end(err)
+		return err
+	}
+}
+
+// handler panic 时按 Internal 打印 RE 日志，并记录 panic 的内容，随后继续 panic，同 zloghttp
+func recoverEnd(end func(err error, fields ...zlog.Field)) {
+	if p := recover(); p != nil {
+		end(status.Error(codes.Internal, "panic"), zlog.Any(zlog.LK_ERR, p))
+		panic(p)
+	}
+}
+
+// UnaryClientInterceptor 返回客户端的 unary 拦截器，opts 为 nil 时使用默认配置
+func UnaryClientInterceptor(opts *Options) grpc.UnaryClientInterceptor {
+	i := newInterceptor(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		start := time.Now()
+		ctx, reqId := i.outgoing(ctx)
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		i.logCall(reqId, cc.Target(), method, start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor 返回客户端的 stream 拦截器，opts 为 nil 时使用默认配置
+// 日志在 stream 结束时打印，cost 为整个 stream 的耗时，stream 结束指以下情况之一：
+// - RecvMsg 返回错误或 io.EOF
+// - 服务端不是 stream（如 client-streaming 的 CloseAndRecv），RecvMsg 成功返回
+// - 调用方的 ctx 结束（取消或超时），适用于未读完即放弃的 stream
+func StreamClientInterceptor(opts *Options) grpc.StreamClientInterceptor {
+	i := newInterceptor(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		ctx, reqId := i.outgoing(ctx)
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			i.logCall(reqId, cc.Target(), method, start, err)
+			return nil, err
+		}
+		return newClientStream(ctx, cs, desc, func(err error) {
+			i.logCall(reqId, cc.Target(), method, start, err)
+		}), nil
+	}
+}
+
+func (this *interceptor) getLogger() *zlog.Logger {
+	if this.logger != nil {
+		return this.logger
+	}
+	return zlog.Default()
+}
+
+// 打印 RB 日志，返回携带 reqId 的 ctx 及打印 RE 日志的方法
+func (this *interceptor) begin(ctx context.Context, method string) (context.Context, func(err error, fields ...zlog.Field)) {
+	reqId, reqHost := "", ""
+	if md, isOK := metadata.FromIncomingContext(ctx); isOK {
+		if vals := md.Get(this.reqIdKey); len(vals) != 0 {
+			reqId = zlog.NormalizeReqID(vals[0])
+		}
+		if vals := md.Get(":authority"); len(vals) != 0 {
+			reqHost = vals[0]
+		}
+	}
+	if reqId == "" {
+		reqId = this.genReqId(ctx)
+	}
+	peerAddr := ""
+	if p, isOK := peer.FromContext(ctx); isOK && p.Addr != nil {
+		peerAddr = p.Addr.String()
+	}
+
+	l := this.getLogger()
+	start := time.Now()
+	l.LogReqBeginw(zlog.LL_INFO, reqId, peerAddr, method, "", 0)
+	return zlog.NewContext(ctx, reqId), func(err error, fields ...zlog.Field) {
+		// 与 zloghttp 的 RE 日志格式一致，级别由 CodeLevel 决定，gRPC 无法取得返回数据的大小，不输出 retSize
+		st := status.Convert(err)
+		l.LogReqEndCodeLevel(this.codeLevel(st.Code()), reqId, reqHost, st.Code().String(), -1, st.Message(), start, fields...)
+	}
+}
+
+// 将 ctx 中的 reqId 写入 outgoing metadata，已存在时不覆盖
+func (this *interceptor) outgoing(ctx context.Context) (context.Context, string) {
+	if md, isOK := metadata.FromOutgoingContext(ctx); isOK {
+		if vals := md.Get(this.reqIdKey); len(vals) != 0 {
+			return ctx, vals[0]
+		}
+	}
+	reqId := zlog.ReqIdFromContext(ctx)
+	if reqId == "" {
+		return ctx, ""
+	}
+	return metadata.AppendToOutgoingContext(ctx, this.reqIdKey, reqId), reqId
+}
+
+func (this *interceptor) logCall(reqId, target, method string, start time.Time, err error) {
+	st := status.Convert(err)
+	fields := []zlog.Field{zlog.String(zlog.LK_RET_CODE, st.Code().String())}
+	if err != nil {
+		fields = append(fields, zlog.Err(err))
+	}
+	// ctx 中没有 reqId 时不输出 reqId 字段，同 zlog.LogCtx
+	if reqId == "" {
+		this.getLogger().LogThirdPartSince(this.codeLevel(st.Code()), this.clientObj, target, method, start, fields...)
+		return
+	}
+	this.getLogger().LogReqThirdPartSince(this.codeLevel(st.Code()), this.clientObj, reqId, target, method, start, fields...)
+}
+
+// serverStream 替换 ServerStream 的 context，使 handler 可以取到 reqId
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (this *serverStream) Context() context.Context {
+	return this.ctx
+}
+
+// clientStream 在 stream 结束时打印日志，见 StreamClientInterceptor
+type clientStream struct {
+	grpc.ClientStream
+	desc *grpc.StreamDesc
+	once sync.Once
+	done chan struct{}
+	end  func(err error)
+}
+
+// ctx 可能结束时启动 goroutine 等待 ctx 结束，stream 结束（见 finish）时 goroutine 随之退出；
+// ctx 不会结束时（如 context.Background()）不启动 goroutine，日志由 RecvMsg 打印
+func newClientStream(ctx context.Context, cs grpc.ClientStream, desc *grpc.StreamDesc, end func(err error)) *clientStream {
+	s := &clientStream{ClientStream: cs, desc: desc, done: make(chan struct{}), end: end}
+	if ctx.Done() == nil {
+		return s
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.finish(status.FromContextError(ctx.Err()).Err())
+		case <-s.done:
+		}
+	}()
+	return s
+}
+
+func (this *clientStream) RecvMsg(m interface{}) error {
+	err := this.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		this.finish(nil)
+	case err != nil:
+		this.finish(err)
+	case !this.desc.ServerStreams:
+		this.finish(nil)
+	}
+	return err
+}
+
+// 只打印一次日志，并使等待 ctx 的 goroutine 退出
+func (this *clientStream) finish(err error) {
+	this.once.Do(func() {
+		close(this.done)
+		this.end(err)
+	})
+}
@@ -0,0 +1,269 @@
+package zloggrpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fevin/zlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// reqIdHealthServer 在 health 服务的基础上，记录 handler 中取到的 reqId
+type reqIdHealthServer struct {
+	*health.Server
+	reqIds chan string
+}
+
+func (this *reqIdHealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	this.reqIds <- zlog.ReqIdFromContext(ctx)
+	return this.Server.Check(ctx, req)
+}
+
+func (this *reqIdHealthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	this.reqIds <- zlog.ReqIdFromContext(stream.Context())
+	return status.Error(codes.Unimplemented, "watch is disabled")
+}
+
+func TestInterceptors(t *testing.T) {
+	dir := t.TempDir()
+	l, err := zlog.New(&zlog.LogConfig{LogDirName: dir, LogFileName: "test.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &Options{Logger: l, GenReqId: func(context.Context) string { return "gen-1" }}
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(opts)),
+		grpc.StreamInterceptor(StreamServerInterceptor(opts)),
+		grpc.UnknownServiceHandler(testStreamHandler),
+	)
+	hs := &reqIdHealthServer{Server: health.NewServer(), reqIds: make(chan string, 4)}
+	healthpb.RegisterHealthServer(s, hs)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(opts)),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(opts)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	// reqId 由客户端通过 metadata 传递
+	ctx := zlog.NewContext(context.Background(), "req-1")
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if reqId := <-hs.reqIds; reqId != "req-1" {
+		t.Errorf("server reqId should be req-1, got %s", reqId)
+	}
+
+	// 没有 reqId 时由服务端生成
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("check unknown service should return NotFound: %v", err)
+	}
+	if reqId := <-hs.reqIds; reqId != "gen-1" {
+		t.Errorf("server reqId should be gen-1, got %s", reqId)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), DefaultReqIdKey, "req-2")
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Unimplemented {
+		t.Fatalf("watch should return Unimplemented: %v", err)
+	}
+	if reqId := <-hs.reqIds; reqId != "req-2" {
+		t.Errorf("server reqId should be req-2, got %s", reqId)
+	}
+	// client-streaming 的 CloseAndRecv 只调用一次 RecvMsg，成功返回时即打印日志
+	cs, err := conn.NewStream(context.Background(), &grpc.StreamDesc{ClientStreams: true}, "/test.Stream/Upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.SendMsg(&healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.RecvMsg(new(healthpb.HealthCheckResponse)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未读完即取消的 stream 在 ctx 结束时打印日志
+	ctx, cancel := context.WithCancel(zlog.NewContext(context.Background(), "req-3"))
+	if _, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, "/test.Stream/Wait"); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	waitLog(t, l, dir, "info=/test.Stream/Wait\tcost=")
+
+	s.GracefulStop()
+	l.Close()
+
+	content := readLog(t, dir)
+	for _, expected := range []string{
+		"logLev=[INFO]\t\tobj=RB\treqId=req-1\treqClientIP=bufconn\treqUri=/grpc.health.v1.Health/Check\treqParams=\tcost=0\n",
+		"logLev=[INFO]\t\tobj=RE\treqId=req-1\treqHost=bufnet\tretCode=OK\tretData=\tcost=",
+		"logLev=[INFO]\t\tobj=GRPC\treqId=req-1\thost=passthrough:///bufnet\tinfo=/grpc.health.v1.Health/Check\tcost=",
+		"\tretCode=OK\n",
+		"obj=RB\treqId=gen-1\t",
+		"logLev=[WARN]\t\tobj=RE\treqId=gen-1\treqHost=bufnet\tretCode=NotFound\tretData=unknown service\tcost=",
+		"logLev=[WARN]\t\tobj=GRPC\thost=passthrough:///bufnet\tinfo=/grpc.health.v1.Health/Check\tcost=",
+		"\tretCode=NotFound\terr=rpc error: code = NotFound desc = unknown service\n",
+		"logLev=[ERROR]\t\tobj=RE\treqId=req-2\treqHost=bufnet\tretCode=Unimplemented\tretData=watch is disabled\tcost=",
+		"logLev=[ERROR]\t\tobj=GRPC\treqId=req-2\thost=passthrough:///bufnet\tinfo=/grpc.health.v1.Health/Watch\tcost=",
+		"\tretCode=Unimplemented\terr=rpc error: code = Unimplemented desc = watch is disabled\n",
+		"logLev=[INFO]\t\tobj=GRPC\thost=passthrough:///bufnet\tinfo=/test.Stream/Upload\tcost=",
+		"logLev=[WARN]\t\tobj=GRPC\treqId=req-3\thost=passthrough:///bufnet\tinfo=/test.Stream/Wait\tcost=",
+		"\tretCode=Canceled\terr=rpc error: code = Canceled desc = context canceled\n",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("log not found: %q in %s", expected, content)
+		}
+	}
+	if !strings.Contains(content, "zloggrpc/interceptor.go:") {
+		t.Errorf("caller is error: %s", content)
+	}
+}
+
+// 处理未注册的方法：Upload 为 client-streaming，读完请求后返回一个响应；Wait 一直等待至 stream 结束
+func testStreamHandler(srv interface{}, ss grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(ss)
+	switch method {
+	case "/test.Stream/Upload":
+		for {
+			err := ss.RecvMsg(new(healthpb.HealthCheckRequest))
+			if err == io.EOF {
+				return ss.SendMsg(&healthpb.HealthCheckResponse{})
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case "/test.Stream/Wait":
+		<-ss.Context().Done()
+		return status.FromContextError(ss.Context().Err()).Err()
+	}
+	return status.Error(codes.Unimplemented, method)
+}
+
+func readLog(t *testing.T, dir string) string {
+	bs, err := os.ReadFile(filepath.Join(dir, "test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(bs)
+}
+
+// 等待异步打印的日志写入文件
+func waitLog(t *testing.T, l *zlog.Logger, dir, expected string) {
+	for i := 0; i < 100; i++ {
+		l.Sync()
+		if strings.Contains(readLog(t, dir), expected) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("log not found: %q", expected)
+}
+
+func TestServerInterceptorPanic(t *testing.T) {
+	dir := t.TempDir()
+	l, err := zlog.New(&zlog.LogConfig{LogDirName: dir, LogFileName: "test.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &Options{Logger: l, GenReqId: func(context.Context) string { return "gen-1" }}
+	unary := UnaryServerInterceptor(opts)
+	stream := StreamServerInterceptor(opts)
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("panic should be re-raised, got %v", p)
+			}
+		}()
+		unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Panic/Unary"},
+			func(context.Context, interface{}) (interface{}, error) { panic("boom") })
+	}()
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("panic should be re-raised, got %v", p)
+			}
+		}()
+		stream(nil, &ctxServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/test.Panic/Stream"},
+			func(interface{}, grpc.ServerStream) error { panic("boom") })
+	}()
+	l.Close()
+
+	content := readLog(t, dir)
+	expected := "logLev=[ERROR]\t\tobj=RE\treqId=gen-1\tretCode=Internal\tretData=panic\tcost="
+	if strings.Count(content, expected) != 2 || strings.Count(content, "\terr=boom\n") != 2 {
+		t.Errorf("log not found: %q in %s", expected, content)
+	}
+}
+
+// ctxServerStream 只实现 Context，用于直接调用拦截器
+type ctxServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (this *ctxServerStream) Context() context.Context {
+	return this.ctx
+}
+
+// eofClientStream 的 RecvMsg 直接返回 io.EOF
+type eofClientStream struct {
+	grpc.ClientStream
+}
+
+func (eofClientStream) RecvMsg(interface{}) error {
+	return io.EOF
+}
+
+// stream 结束时打印一次日志，之后 ctx 结束不会再次打印
+func TestClientStreamFinish(t *testing.T) {
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	ends := 0
+	for _, c := range []context.Context{context.Background(), ctx} {
+		s := newClientStream(c, eofClientStream{}, desc, func(error) { ends++ })
+		s.RecvMsg(nil)
+		s.RecvMsg(nil)
+		select {
+		case <-s.done:
+		default:
+			t.Error("stream should be finished after EOF")
+		}
+	}
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if ends != 2 {
+		t.Errorf("end should be called once per stream, got %d", ends)
+	}
+}
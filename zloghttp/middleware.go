@@ -0,0 +1,233 @@
+// zloghttp 提供 net/http 的中间件，为每个请求打印 RB/RE 日志：
+//
+//	http.ListenAndServe(":8080", zloghttp.Middleware(mux, nil))
+//
+// RB 日志记录 reqId、客户端 IP、uri（转义后的 path）、参数及 method，RE 日志记录 reqHost、状态码（retCode）、返回数据大小（retSize）及耗时，
+// 日志级别由状态码决定，见 zlog.LogConfig.RetCodeLevel
+//
+// reqId 会存入 request 的 context，handler 中可以通过 zlog.LogCtx* 打印带有 reqId 的日志，
+// 或通过 zlog.ReqIdFromContext 取出 reqId；开启 Options.Trace 时，可以通过 zlog.TraceFromContext 取出 TraceContext，
+// 调用下游时将 TraceContext.Traceparent() 写入 traceparent header
+package zloghttp
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/fevin/zlog"
+)
+
+const (
+	DefaultReqIdHeader = "X-Request-Id"
+
+	LK_METHOD = "method"
+)
+
+type Options struct {
+	// 打印日志使用的 Logger，为 nil 时使用 zlog 的默认 Logger
+	Logger *zlog.Logger
+
+	// 传递 reqId 的 header，请求中带有合法的 reqId（见 zlog.NormalizeReqID）时沿用，否则生成新的 reqId；
+	// 返回时在该 header 中回写 reqId。默认为 X-Request-Id
+	ReqIdHeader string
+
+	// 生成 reqId 的方法，默认为 zlog.NewReqID
+	GenReqId func(r *http.Request) string
+
+	// 可信的代理，支持 IP 或 CIDR，如 "10.0.0.0/8"
+	// 只有直连的地址属于可信代理时，才会通过 X-Forwarded-For/X-Real-IP 获取客户端 IP
+	TrustedProxies []string
+
+	// 是否记录 W3C trace context：从请求的 traceparent header 中解析上游的 span（不存在或不合法时生成新的调用链），
+	// 为当前请求生成新的 span，RB/RE 及 LogCtx* 日志会带有 traceId、spanId、parentSpanId 字段，见 zlog.TraceContext
+	Trace bool
+}
+
+type middleware struct {
+	next           http.Handler
+	logger         *zlog.Logger
+	reqIdHeader    string
+	genReqId       func(r *http.Request) string
+	trustedProxies []*net.IPNet
+	trace          bool
+}
+
+// Middleware 返回打印 RB/RE 日志的 handler，opts 为 nil 时使用默认配置
+// TrustedProxies 中有无法解析的地址时 panic
+func Middleware(next http.Handler, opts *Options) http.Handler {
+	if opts == nil {
+		opts = new(Options)
+	}
+	m := &middleware{
+		next:        next,
+		logger:      opts.Logger,
+		reqIdHeader: opts.ReqIdHeader,
+		genReqId:    opts.GenReqId,
+		trace:       opts.Trace,
+	}
+	if m.reqIdHeader == "" {
+		m.reqIdHeader = DefaultReqIdHeader
+	}
+	if m.genReqId == nil {
+		m.genReqId = func(*http.Request) string { return zlog.NewReqID() }
+	}
+	for _, proxy := range opts.TrustedProxies {
+		ipNet, err := parseIPNet(proxy)
+		if err != nil {
+			panic("zloghttp: " + err.Error())
+		}
+		m.trustedProxies = append(m.trustedProxies, ipNet)
+	}
+	return m
+}
+
+func (this *middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reqId := zlog.NormalizeReqID(r.Header.Get(this.reqIdHeader))
+	if reqId == "" {
+		reqId = this.genReqId(r)
+	}
+	w.Header().Set(this.reqIdHeader, reqId)
+
+	var tc zlog.TraceContext
+	if this.trace {
+		parent, _ := zlog.ParseTraceparent(r.Header.Get(zlog.TraceparentHeader))
+		tc = parent.NewChild()
+	}
+
+	// 使用转义后的 path，避免 %0A、%09 等解码后的换行、tab 写入日志，伪造字段或日志行
+	uri := r.URL.EscapedPath()
+	var req *zlog.Request
+	if this.logger != nil {
+		req = this.logger.BeginRequestTrace(reqId, this.clientIP(r), uri, r.URL.RawQuery, tc, zlog.String(LK_METHOD, r.Method))
+	} else {
+		req = zlog.BeginRequestTrace(reqId, this.clientIP(r), uri, r.URL.RawQuery, tc, zlog.String(LK_METHOD, r.Method))
+	}
+
+	rw := &responseWriter{ResponseWriter: w}
+	defer func() {
+		// next panic 时按 500 打印 RE 日志，并记录 panic 的内容，随后继续 panic，交由 net/http 处理
+		if p := recover(); p != nil {
+			req.EndCode(r.Host, http.StatusInternalServerError, rw.size, "", zlog.Any(zlog.LK_ERR, p))
+			panic(p)
+		}
+		req.EndCode(r.Host, rw.statusCode(), rw.size, "")
+	}()
+	this.next.ServeHTTP(rw, r.WithContext(req.Context(r.Context())))
+}
+
+// 获取客户端 IP：直连地址不是可信代理时直接返回；
+// 否则从右向左查找 X-Forwarded-For（多个 header 按顺序合并）中第一个不是可信代理的地址，其次是 X-Real-IP；
+// 不是合法 IP 的值会被忽略，避免客户端伪造的内容写入日志
+func (this *middleware) clientIP(r *http.Request) string {
+	remoteIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remoteIP = host
+	}
+	if !this.trusted(remoteIP) {
+		return remoteIP
+	}
+
+	if xff := strings.Join(r.Header.Values("X-Forwarded-For"), ","); xff != "" {
+		// 全部是可信代理时，返回最左边的地址
+		leftmost := ""
+		ips := strings.Split(xff, ",")
+		for i := len(ips) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(ips[i])
+			if net.ParseIP(ip) == nil {
+				continue
+			}
+			if !this.trusted(ip) {
+				return ip
+			}
+			leftmost = ip
+		}
+		if leftmost != "" {
+			return leftmost
+		}
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return remoteIP
+}
+
+func (this *middleware) trusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range this.trustedProxies {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseIPNet(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, ipNet, err := net.ParseCIDR(s)
+		return ipNet, err
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, errors.New("invalid trusted proxy: " + s)
+	}
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// responseWriter 记录返回的状态码及数据大小
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (this *responseWriter) WriteHeader(statusCode int) {
+	if this.status == 0 {
+		this.status = statusCode
+	}
+	this.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (this *responseWriter) Write(b []byte) (int, error) {
+	if this.status == 0 {
+		this.status = http.StatusOK
+	}
+	n, err := this.ResponseWriter.Write(b)
+	this.size += n
+	return n, err
+}
+
+// 未调用 WriteHeader/Write 时，net/http 默认返回 200
+func (this *responseWriter) statusCode() int {
+	if this.status == 0 {
+		return http.StatusOK
+	}
+	return this.status
+}
+
+func (this *responseWriter) Flush() {
+	if f, isOK := this.ResponseWriter.(http.Flusher); isOK {
+		f.Flush()
+	}
+}
+
+func (this *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, isOK := this.ResponseWriter.(http.Hijacker); isOK {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("zloghttp: the ResponseWriter doesnot implement http.Hijacker")
+}
+
+// 返回原始的 ResponseWriter，供 http.ResponseController 使用
+func (this *responseWriter) Unwrap() http.ResponseWriter {
+	return this.ResponseWriter
+}
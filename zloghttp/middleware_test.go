@@ -0,0 +1,184 @@
+package zloghttp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fevin/zlog"
+)
+
+func newTestLogger(t *testing.T) (*zlog.Logger, string) {
+	dir, err := ioutil.TempDir("", "zloghttp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	l, err := zlog.New(&zlog.LogConfig{LogDirName: dir, LogFileName: "test.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, dir
+}
+
+func readLogFile(t *testing.T, dir string) string {
+	bs, err := ioutil.ReadFile(filepath.Join(dir, "test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(bs)
+}
+
+func TestMiddleware(t *testing.T) {
+	l, dir := newTestLogger(t)
+	var ctxReqId string
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxReqId = zlog.ReqIdFromContext(r.Context())
+		l.LogCtx(r.Context(), zlog.LL_INFO, "USER", "get user")
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("hello"))
+	}), &Options{Logger: l, GenReqId: func(*http.Request) string { return "gen-1" }})
+
+	req := httptest.NewRequest("GET", "http://api.example.com/user?id=1", nil)
+	req.RemoteAddr = "192.168.1.2:5678"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get(DefaultReqIdHeader) != "gen-1" || ctxReqId != "gen-1" {
+		t.Errorf("reqId is error: header=%s ctx=%s", rec.Header().Get(DefaultReqIdHeader), ctxReqId)
+	}
+
+	req = httptest.NewRequest("POST", "http://api.example.com/missing", nil)
+	req.Header.Set(DefaultReqIdHeader, "client-1")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get(DefaultReqIdHeader) != "client-1" || ctxReqId != "client-1" {
+		t.Errorf("reqId is error: header=%s ctx=%s", rec.Header().Get(DefaultReqIdHeader), ctxReqId)
+	}
+
+	// 非法的 reqId 会被替换
+	req = httptest.NewRequest("GET", "http://api.example.com/user", nil)
+	req.Header.Set(DefaultReqIdHeader, "forged\tinfo=x")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get(DefaultReqIdHeader) != "gen-1" {
+		t.Errorf("invalid reqId should be replaced: %s", rec.Header().Get(DefaultReqIdHeader))
+	}
+	l.Close()
+
+	content := readLogFile(t, dir)
+	for _, expected := range []string{
+		"logLev=[INFO]\t\tobj=RB\treqId=gen-1\treqClientIP=192.168.1.2\treqUri=/user\treqParams=id=1\tcost=0\tmethod=GET\n",
+		"logLev=[INFO]\t\tobj=USER\treqId=gen-1\tinfo=get user\n",
+		"logLev=[INFO]\t\tobj=RE\treqId=gen-1\treqHost=api.example.com\tretCode=200\tretSize=5\tretData=\tcost=",
+		"obj=RB\treqId=client-1\treqClientIP=192.0.2.1\treqUri=/missing\treqParams=\tcost=0\tmethod=POST\n",
+		"logLev=[WARN]\t\tobj=RE\treqId=client-1\treqHost=api.example.com\tretCode=404\tretSize=19\tretData=\tcost=",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("log not found: %q in %s", expected, content)
+		}
+	}
+}
+
+// 解码后的 path 中的换行、tab 不会写入日志
+func TestMiddlewareEscapedPath(t *testing.T) {
+	l, dir := newTestLogger(t)
+	h := Middleware(http.NotFoundHandler(), &Options{Logger: l, GenReqId: func(*http.Request) string { return "gen-1" }})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://api.example.com/a%0Ats=forged%09k=v", nil))
+	l.Close()
+
+	content := readLogFile(t, dir)
+	if !strings.Contains(content, "\treqUri=/a%0Ats=forged%09k=v\treqParams=\t") {
+		t.Errorf("reqUri should be escaped: %s", content)
+	}
+	if lines := strings.Split(strings.TrimSpace(content), "\n"); len(lines) != 2 || strings.Contains(content, "\tk=v") {
+		t.Errorf("log should not be split or have forged fields: %s", content)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	m := Middleware(http.NotFoundHandler(), &Options{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"}}).(*middleware)
+	cases := []struct {
+		remoteAddr string
+		headers    map[string][]string
+		expected   string
+	}{
+		{"1.2.3.4:80", map[string][]string{"X-Forwarded-For": {"5.6.7.8"}}, "1.2.3.4"},
+		{"10.0.0.1:80", map[string][]string{"X-Forwarded-For": {"5.6.7.8, 9.9.9.9, 10.0.0.2"}}, "9.9.9.9"},
+		{"192.168.1.1:80", map[string][]string{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}}, "10.0.0.3"},
+		{"10.0.0.1:80", map[string][]string{"X-Real-IP": {"5.6.7.8"}}, "5.6.7.8"},
+		{"10.0.0.1:80", nil, "10.0.0.1"},
+		// 可信代理追加的 X-Forwarded-For header 在客户端伪造的 header 之后
+		{"10.0.0.1:80", map[string][]string{"X-Forwarded-For": {"6.6.6.6", "9.9.9.9"}}, "9.9.9.9"},
+		// 不合法的值被忽略
+		{"10.0.0.1:80", map[string][]string{"X-Forwarded-For": {"1.2.3.4\tinfo=x"}, "X-Real-IP": {"5.6.7.8\tinfo=x"}}, "10.0.0.1"},
+		{"10.0.0.1:80", map[string][]string{"X-Forwarded-For": {"9.9.9.9, bad, 10.0.0.2"}}, "9.9.9.9"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = c.remoteAddr
+		for k, vals := range c.headers {
+			for _, v := range vals {
+				r.Header.Add(k, v)
+			}
+		}
+		if ip := m.clientIP(r); ip != c.expected {
+			t.Errorf("clientIP of %s %v should be %s, got %s", c.remoteAddr, c.headers, c.expected, ip)
+		}
+	}
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	l, dir := newTestLogger(t)
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), &Options{Logger: l, GenReqId: func(*http.Request) string { return "gen-1" }})
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("panic should be re-raised, got %v", p)
+			}
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://api.example.com/user", nil))
+	}()
+	l.Close()
+
+	expected := "logLev=[ERROR]\t\tobj=RE\treqId=gen-1\treqHost=api.example.com\tretCode=500\tretSize=0\tretData=\tcost="
+	if content := readLogFile(t, dir); !strings.Contains(content, expected) || !strings.Contains(content, "\terr=boom\n") {
+		t.Errorf("log not found: %q in %s", expected, content)
+	}
+}
+
+func TestMiddlewareTrace(t *testing.T) {
+	l, dir := newTestLogger(t)
+	var tc zlog.TraceContext
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tc, _ = zlog.TraceFromContext(r.Context())
+		l.LogCtx(r.Context(), zlog.LL_INFO, "USER", "get user")
+	}), &Options{Logger: l, Trace: true})
+
+	req := httptest.NewRequest("GET", "http://api.example.com/user", nil)
+	req.Header.Set(zlog.TraceparentHeader, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if tc.TraceId != "0af7651916cd43dd8448eb211c80319c" || tc.ParentSpanId != "b7ad6b7169203331" || len(tc.SpanId) != 16 {
+		t.Fatalf("trace context is error: %+v", tc)
+	}
+	l.Close()
+
+	content := readLogFile(t, dir)
+	traceKV := "traceId=0af7651916cd43dd8448eb211c80319c\tspanId=" + tc.SpanId + "\tparentSpanId=b7ad6b7169203331"
+	for _, obj := range []string{"obj=RB\t", "obj=USER\t", "obj=RE\t"} {
+		for _, line := range strings.Split(content, "\n") {
+			if strings.Contains(line, obj) && !strings.Contains(line, traceKV) {
+				t.Errorf("trace fields not found: %s", line)
+			}
+		}
+	}
+}
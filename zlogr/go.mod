@@ -0,0 +1,15 @@
+module github.com/fevin/zlog/zlogr
+
+go 1.21
+
+require (
+	github.com/fevin/zlog v1.0.0
+	github.com/go-logr/logr v1.4.3
+	go.uber.org/zap v1.15.0
+)
+
+require (
+	go.uber.org/atomic v1.6.0 // indirect
+	go.uber.org/multierr v1.5.0 // indirect
+	gopkg.in/natefinch/lumberjack.v2 v2.0.0 // indirect
+)
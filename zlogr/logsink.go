@@ -0,0 +1,133 @@
+// zlogr 提供 go-logr 的 LogSink，logr 日志以 zlog 的格式写入同一份日志文件：
+//
+//	log := zlogr.New(nil)
+//	log.WithName("CONTROLLER").Info("reconcile", "pod", "web-0")
+//	// => ... logLev=[INFO]		obj=CONTROLLER	info=reconcile	pod=web-0
+//
+// - V(0) 为 INFO，V(1) 及以上为 DEBUG
+// - Error 为 ERROR，与 zlog.LogErr 一致：obj=xxx info=xxx err=xxx
+// - WithName 设置的名字作为 obj 输出（多次 WithName 以 . 连接），未设置时为 LOGR
+// - key/value 以 k=v 的形式追加在末尾
+package zlogr
+
+import (
+	"fmt"
+
+	"github.com/fevin/zlog"
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const DefaultObj = "LOGR"
+
+// New 返回写入 l 的 logr.Logger，l 为 nil 时使用 zlog 的默认 Logger
+func New(l *zlog.Logger) logr.Logger {
+	return logr.New(NewLogSink(l))
+}
+
+// NewLogSink 返回写入 l 的 logr.LogSink，l 为 nil 时使用 zlog 的默认 Logger
+func NewLogSink(l *zlog.Logger) logr.LogSink {
+	if l == nil {
+		l = zlog.Default()
+	}
+	sink := &logSink{base: l.ZapLogger()}
+	sink.build()
+	return sink
+}
+
+type logSink struct {
+	base      *zap.Logger // 未设置 caller skip 及 name
+	zl        *zap.Logger // 由 base、name 及 callDepth 生成，见 build
+	name      string
+	fields    []zap.Field
+	callDepth int // logr 额外的调用层数，见 Init、WithCallDepth
+}
+
+var (
+	_ logr.LogSink          = (*logSink)(nil)
+	_ logr.CallDepthLogSink = (*logSink)(nil)
+)
+
+func (this *logSink) Init(info logr.RuntimeInfo) {
+	this.callDepth += info.CallDepth
+	this.build()
+}
+
+func (this *logSink) Enabled(level int) bool {
+	return this.zl.Check(zapLevel(level), "") != nil
+}
+
+func (this *logSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	if ce := this.zl.Check(zapLevel(level), msg); ce != nil {
+		ce.Write(this.withFields(keysAndValues)...)
+	}
+}
+
+func (this *logSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	if ce := this.zl.Check(zapcore.ErrorLevel, msg); ce != nil {
+		ce.Write(append([]zap.Field{zlog.Err(err)}, this.withFields(keysAndValues)...)...)
+	}
+}
+
+func (this *logSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	sink := *this
+	sink.fields = this.withFields(keysAndValues)
+	return &sink
+}
+
+func (this *logSink) WithName(name string) logr.LogSink {
+	sink := *this
+	if sink.name == "" {
+		sink.name = name
+	} else {
+		sink.name += "." + name
+	}
+	sink.build()
+	return &sink
+}
+
+func (this *logSink) WithCallDepth(depth int) logr.LogSink {
+	sink := *this
+	sink.callDepth += depth
+	sink.build()
+	return &sink
+}
+
+// 生成打印日志使用的 zap.Logger：name 作为 obj，跳过 logSink 自身及 logr 的调用层数，使 file 为调用 logr 的位置
+func (this *logSink) build() {
+	name := this.name
+	if name == "" {
+		name = DefaultObj
+	}
+	this.zl = this.base.WithOptions(zap.AddCallerSkip(1 + this.callDepth)).Named(name)
+}
+
+// 返回 WithValues 绑定的字段及 keysAndValues 转换后的字段
+func (this *logSink) withFields(keysAndValues []interface{}) []zap.Field {
+	fields := make([]zap.Field, 0, len(this.fields)+(len(keysAndValues)+1)/2)
+	fields = append(fields, this.fields...)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, isOK := keysAndValues[i].(string)
+		if !isOK {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		if i+1 >= len(keysAndValues) {
+			fields = append(fields, zap.Any(key, "(MISSING)"))
+			break
+		}
+		value := keysAndValues[i+1]
+		if m, isOK := value.(logr.Marshaler); isOK {
+			value = m.MarshalLog()
+		}
+		fields = append(fields, zap.Any(key, value))
+	}
+	return fields
+}
+
+func zapLevel(level int) zapcore.Level {
+	if level > 0 {
+		return zapcore.DebugLevel
+	}
+	return zapcore.InfoLevel
+}
@@ -0,0 +1,58 @@
+package zlogr
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fevin/zlog"
+)
+
+type user struct{ id int }
+
+func (u user) MarshalLog() interface{} {
+	return map[string]int{"id": u.id}
+}
+
+func TestLogSink(t *testing.T) {
+	dir := t.TempDir()
+	l, err := zlog.New(&zlog.LogConfig{LogDirName: dir, LogFileName: "test.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := New(l)
+	log.Info("start", "version", "1.0")
+	log.V(1).Info("hidden")
+	ctrl := log.WithName("CONTROLLER").WithValues("ns", "default")
+	ctrl.WithName("POD").Info("reconcile", "pod", "web-0", "user", user{1}, "odd")
+	ctrl.Error(errors.New("timeout"), "sync failed", "retry", 3)
+	if log.V(1).Enabled() || !log.V(0).Enabled() {
+		t.Error("V(1) should be disabled and V(0) enabled at INFO level")
+	}
+	l.SetLevel(zlog.LL_DEBUG)
+	log.V(2).Info("visible")
+	log.WithCallDepth(0).Info("depth")
+	l.Close()
+
+	bs, err := os.ReadFile(filepath.Join(dir, "test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(bs)), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("log should have 5 lines: %s", bs)
+	}
+	for i, expected := range []string{
+		"logLev=[INFO]\t\tobj=LOGR\tinfo=start\tversion=1.0",
+		"logLev=[INFO]\t\tobj=CONTROLLER.POD\tinfo=reconcile\tns=default\tpod=web-0\tuser={\"id\":1}\todd=(MISSING)",
+		"logLev=[ERROR]\t\tobj=CONTROLLER\tinfo=sync failed\terr=timeout\tns=default\tretry=3",
+		"logLev=[DEBUG]\t\tobj=LOGR\tinfo=visible",
+		"logLev=[INFO]\t\tobj=LOGR\tinfo=depth",
+	} {
+		if !strings.HasSuffix(lines[i], expected) || !strings.Contains(lines[i], "zlogr/logsink_test.go:") {
+			t.Errorf("logr line %d is error:\n%s\nexpected suffix:\n%s", i, lines[i], expected)
+		}
+	}
+}